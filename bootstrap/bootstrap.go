@@ -10,6 +10,7 @@ import (
 	"go-pipeline/internal/di"
 	"go-pipeline/internal/presentation/http"
 	"go-pipeline/internal/presentation/mq"
+	"go-pipeline/internal/stages"
 
 	"go-pipeline/config"
 	"go-pipeline/infrastructure/registry"
@@ -21,6 +22,7 @@ type App struct {
 	sync.WaitGroup
 	httpServer *registry.HTTPServerRegistry
 	mq         *registry.MQRegistry
+	cache      *registry.CacheRegistry
 	pipelines  *di.Pipelines
 	stages     *di.Stages
 }
@@ -33,7 +35,15 @@ func Initialize(ctx context.Context) (*App, error) {
 
 	app := &App{}
 
-	// 1) initialize databases
+	// 1) initialize databases and caches
+	app.cache, err = registry.NewCacheRegistry(ctx)
+	if err != nil {
+		return nil, err
+	}
+	log.Info(&logger.Log{
+		Event:   "initialize caches",
+		TraceID: traceID,
+	})
 
 	// 2) initialize message queue
 	handler := mq.NewConsumerHandler()
@@ -47,17 +57,37 @@ func Initialize(ctx context.Context) (*App, error) {
 	})
 	app.mq = mqRegistry
 	// 3) initialize stages
-	app.stages = di.NewStagesContainer(app.mq.GetKafkaProducer())
+	app.stages, err = di.NewStagesContainer(app.mq.GetKafkaProducer(), app.cache.GetDedupStore())
+	if err != nil {
+		return nil, err
+	}
 
 	// 4) initialize pipelines
-	app.pipelines = di.NewPipelines(app.stages)
+	app.pipelines, err = di.NewPipelines(app.stages)
+	if err != nil {
+		return nil, err
+	}
 
 	// 5) initialize httpserver server
 	handlerHTTP := http.NewGinAdapter(
 		app.pipelines.Parallel,
 		app.pipelines.Barrier,
 		app.pipelines.Short,
+		app.pipelines.DAG,
+		app.stages.ProducerBreaker,
+		app.stages.DeadLetterBreaker,
 	)
+	handlerHTTP.ServeStages(stages.Registry)
+	handler.WithDeadLetter(app.stages.RawDeadLetter)
+	// pipelines declared in the config start on their route or topic
+	for _, t := range app.pipelines.Triggers {
+		if t.Route != "" {
+			handlerHTTP.Handle(t.Pipeline, t.Route, t.Run)
+		}
+		if t.Topic != "" {
+			handler.Route(t.Topic, t.Run)
+		}
+	}
 	httpRegistry := registry.NewHTTPServerRegistry(handlerHTTP.Engin)
 	app.httpServer = httpRegistry
 	log.Info(&logger.Log{
@@ -141,6 +171,18 @@ func (app *App) Stop(ctx context.Context) {
 			})
 		}
 	}
+
+	// Close caches
+	if app.cache != nil {
+		if err := app.cache.Close(); err != nil {
+			logger.GetLogger().Error(&logger.Log{
+				Event:      "stop app",
+				Error:      err,
+				TraceID:    traceID,
+				Additional: map[string]interface{}{"msg": "failed to stop caches"},
+			})
+		}
+	}
 }
 
 // GracefulShutdown handles the graceful shutdown of the application.
@@ -20,7 +20,9 @@ type Config struct {
 	DBConfig         []DBConfig       `json:"databases"   yaml:"databases"`
 	HTTPServer       HTTPServer       `json:"http_server" yaml:"http_server"`
 	MQConfig         []MQConfig       `json:"mq"          yaml:"mq"`
+	CacheConfig      []CacheConfig    `json:"caches"      yaml:"caches"`
 	WorkerPoolConfig WorkerPoolConfig `json:"worker_pool" yaml:"worker_pool"`
+	Pipelines        []PipelineConfig `json:"pipelines"   yaml:"pipelines"`
 }
 
 // AppConfig holds configuration settings for the application.
@@ -65,7 +67,20 @@ type MQConfig struct {
 	Topics  []string `json:"topics"                       yaml:"topics"`
 }
 
+// CacheConfig holds configuration settings for a single cache. The only
+// type so far is "redis"; the one named "redis-dedup" backs the dedup stage.
+type CacheConfig struct {
+	Name     string `json:"name"     validate:"required" yaml:"name"`
+	Type     string `json:"type"     validate:"required" yaml:"type"`
+	Port     int    `json:"port"     validate:"required" yaml:"port"`
+	Address  string `json:"address"  validate:"required" yaml:"address"`
+	Password string `json:"password"                     yaml:"password"`
+	DB       int    `json:"db"                           yaml:"db"`
+}
+
 // WorkerPoolConfig holds configuration settings for the worker pool.
+// RetryDelay is the base retry backoff in milliseconds and RetryMax the
+// number of retries after the first attempt.
 type WorkerPoolConfig struct {
 	WorkerNum  int `json:"worker_num"  validate:"required" yaml:"worker_num"`
 	QueueSize  int `json:"queue_size"  validate:"required" yaml:"queue_size"`
@@ -73,6 +88,61 @@ type WorkerPoolConfig struct {
 	RetryMax   int `json:"retry_max"   validate:"required" yaml:"retry_max"`
 }
 
+// PipelineConfig holds configuration settings for a single pipeline.
+// An entry with a runner (chain, barrier or short) declares a pipeline, or
+// replaces the built-in pipeline of the same name (parallel, barrier,
+// short). An entry without a runner only tunes the buffers of the pipeline
+// with that name. MaxErrors and MaxRatio are the limits of the threshold
+// error policy.
+type PipelineConfig struct {
+	Name        string        `json:"name"         validate:"required" yaml:"name"`
+	Runner      string        `json:"runner"                           yaml:"runner"`
+	ErrorPolicy string        `json:"error_policy"                     yaml:"error_policy"`
+	MaxErrors   int           `json:"max_errors"                       yaml:"max_errors"`
+	MaxRatio    float64       `json:"max_ratio"                        yaml:"max_ratio"`
+	Trigger     TriggerConfig `json:"trigger"                          yaml:"trigger"`
+	Buffers     BufferConfig  `json:"buffers"                          yaml:"buffers"`
+	Stages      []StageConfig `json:"stages"                           yaml:"stages"`
+}
+
+// TriggerConfig holds what starts a declared pipeline: a POST route under
+// the HTTP API, a Kafka topic, or both.
+type TriggerConfig struct {
+	Route string `json:"route" yaml:"route"`
+	Topic string `json:"topic" yaml:"topic"`
+}
+
+// StageConfig holds configuration settings for a single stage of a pipeline.
+// Stages run in the order they are listed; disabled ones are skipped. The
+// short runner calls its stages one item at a time and ignores Workers.
+type StageConfig struct {
+	Name     string            `json:"name"     validate:"required" yaml:"name"`
+	Disabled bool              `json:"disabled"                     yaml:"disabled"`
+	Workers  int               `json:"workers"                      yaml:"workers"`
+	Options  map[string]string `json:"options"                      yaml:"options"`
+	Buffers  BufferConfig      `json:"buffers"                      yaml:"buffers"`
+}
+
+// BufferConfig holds channel buffer sizes. Zero values fall back to the
+// pipeline's buffers and then to BuffData, BuffErr and BuffBarrierCap.
+// Barrier only applies to a pipeline, it is the item capacity of a barrier
+// phase.
+type BufferConfig struct {
+	Data    int `json:"data"    yaml:"data"`
+	Err     int `json:"err"     yaml:"err"`
+	Barrier int `json:"barrier" yaml:"barrier"`
+}
+
+// Pipeline returns the configuration of the named pipeline.
+func (c *Config) Pipeline(name string) (PipelineConfig, bool) {
+	for _, p := range c.Pipelines {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return PipelineConfig{}, false
+}
+
 // Get returns the singleton instance of the Config struct.
 func Get() *Config {
 	return instance
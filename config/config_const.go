@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 // ******* PATH *******
 
 const (
@@ -17,8 +19,52 @@ const (
 
 // *******Channels Stages*******
 
+// Default buffer sizes, used for every pipeline and stage without a
+// buffers entry in the pipelines config section.
 const (
 	BuffData       int = 64
 	BuffErr        int = 64
 	BuffBarrierCap int = 16
 )
+
+// *******HTTP*******
+
+const (
+	// MaxBodyBytes is the largest request body the HTTP handlers read
+	MaxBodyBytes int64 = 1 << 20
+)
+
+// *******Topics*******
+
+const (
+	// UsersTopic receives every registered user
+	UsersTopic string = "users"
+	// DeadLetterTopic receives items that failed a pipeline stage
+	DeadLetterTopic string = "users-dlq"
+)
+
+// *******Stage Workers*******
+
+const (
+	ValidationWorkers int = 1
+	StoreWorkers      int = 1
+	ProduceWorkers    int = 4
+)
+
+// *******Rate Limits*******
+
+const (
+	// ProduceRate is the number of messages per second sent to Kafka
+	ProduceRate float64 = 200
+	// ProduceBurst is the number of messages that may be sent at once
+	ProduceBurst int = 50
+)
+
+// *******Dedup*******
+
+const (
+	// DedupTTL is how long a produced user is remembered as a duplicate
+	DedupTTL time.Duration = 10 * time.Minute
+	// DedupCapacity is the number of keys held by the in-memory dedup store
+	DedupCapacity int = 10_000
+)
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// dedupPrefix namespaces the dedup keys in a shared Redis.
+const dedupPrefix = "dedup:"
+
+// RedisConfig holds configuration settings for a Redis connection.
+type RedisConfig struct {
+	Name     string // Logical name of the connection
+	Port     int    // Redis port
+	Address  string // Redis host or IP
+	Password string // Password, empty when auth is off
+	DB       int    // Database index
+}
+
+// RedisDedupStore implements ports.DedupStore on Redis, so every replica of
+// the service shares the keys it has seen.
+type RedisDedupStore struct {
+	Config *RedisConfig  // Connection configuration
+	Client *redis.Client // Redis client instance
+}
+
+// Connect creates the client and checks that Redis answers.
+func (s *RedisDedupStore) Connect(ctx context.Context) error {
+	s.Client = redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", s.Config.Address, s.Config.Port),
+		Password: s.Config.Password,
+		DB:       s.Config.DB,
+	})
+	if err := s.Client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("%w: redis %s: %v", apperror.ErrUnavailable, s.Config.Name, err)
+	}
+	return nil
+}
+
+// MarkSeen sets the key only if it does not exist yet (SET NX), which makes
+// the check and the write one atomic step on the server.
+func (s *RedisDedupStore) MarkSeen(
+	ctx context.Context,
+	key string,
+	ttl time.Duration,
+) (bool, error) {
+	res, err := s.Client.SetArgs(ctx, dedupPrefix+key, 1, redis.SetArgs{
+		Mode: "NX",
+		TTL:  ttl,
+	}).Result()
+	switch {
+	case errors.Is(err, redis.Nil):
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("%w: redis %s: %v", apperror.ErrUnavailable, s.Config.Name, err)
+	default:
+		return res == "OK", nil
+	}
+}
+
+// Forget deletes the key, which is a no-op when it is already gone.
+func (s *RedisDedupStore) Forget(ctx context.Context, key string) error {
+	if err := s.Client.Del(ctx, dedupPrefix+key).Err(); err != nil {
+		return fmt.Errorf("%w: redis %s: %v", apperror.ErrUnavailable, s.Config.Name, err)
+	}
+	return nil
+}
+
+// Close closes the client.
+func (s *RedisDedupStore) Close() error {
+	if s.Client == nil {
+		return nil
+	}
+	return s.Client.Close()
+}
+
+var _ ports.DedupStore = (*RedisDedupStore)(nil)
@@ -3,7 +3,10 @@ package message_queue
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"time"
 
 	"go-pipeline/config"
@@ -52,7 +55,8 @@ func (p *KafkaProducerAdapter) Connect(ctx context.Context) error {
 	cfg.Producer.Retry.Max = 10                         // Retry up to 10 times
 	cfg.Producer.Retry.Backoff = 100 * time.Millisecond // Delay between retries
 
-	// 6. Timeout for waiting acknowledgments
+	// 6. Timeout for waiting acknowledgments; SendMessage ignores the context,
+	//    so this and the retries above are what bound a produce
 	cfg.Producer.Timeout = 20 * time.Second
 
 	// 7. Idempotent producer (ensures no duplicate messages on retries)
@@ -111,13 +115,14 @@ func (p *KafkaProducerAdapter) Produce(
 		},
 		Partition: -1,
 	}
+	message.Headers = append(message.Headers, buildHeaders(msg)...)
 
 	// Send the message synchronously and capture partition/offset
 	partition, offset, err := p.Producer.SendMessage(message)
 	if err != nil {
 		return fmt.Errorf(
 			"%w: failed to produce message: %w, partiotion:%d, offset: %d",
-			apperror.ErrUnavailable,
+			produceError(err),
 			err,
 			partition,
 			offset,
@@ -126,6 +131,29 @@ func (p *KafkaProducerAdapter) Produce(
 	return nil
 }
 
+// produceError classifies a SendMessage error. Only errors that show the
+// message never reached a broker are apperror.ErrUnavailable, which callers
+// retry. After any other failure, e.g. a timed out acknowledgement, the
+// message may have been written, and a retry would duplicate it.
+func produceError(err error) error {
+	switch {
+	case errors.Is(err, sarama.ErrMessageSizeTooLarge),
+		errors.Is(err, sarama.ErrInvalidMessage):
+		return apperror.ErrInvalidInput
+	case errors.Is(err, sarama.ErrOutOfBrokers),
+		errors.Is(err, sarama.ErrNotConnected),
+		errors.Is(err, sarama.ErrClosedClient),
+		errors.Is(err, sarama.ErrShuttingDown),
+		errors.Is(err, sarama.ErrLeaderNotAvailable),
+		errors.Is(err, sarama.ErrNotLeaderForPartition),
+		errors.Is(err, sarama.ErrUnknownTopicOrPartition),
+		errors.Is(err, sarama.ErrNotEnoughReplicas):
+		return apperror.ErrUnavailable
+	default:
+		return apperror.ErrInternal
+	}
+}
+
 // Close gracefully shuts down the Kafka producer connection.
 func (p *KafkaProducerAdapter) Close() error {
 	if p.Producer != nil {
@@ -137,6 +165,24 @@ func (p *KafkaProducerAdapter) Close() error {
 // Ensure KafkaProducerAdapter implements the MessageQueueProducer interface.
 var _ ports.MessageQueueProducer = (*KafkaProducerAdapter)(nil)
 
+// buildHeaders returns the extra headers of messages implementing
+// ports.HeaderCarrier, sorted by key.
+func buildHeaders(value interface{}) []sarama.RecordHeader {
+	carrier, ok := value.(ports.HeaderCarrier)
+	if !ok {
+		return nil
+	}
+	headers := carrier.Headers()
+	records := make([]sarama.RecordHeader, 0, len(headers))
+	for _, k := range slices.Sorted(maps.Keys(headers)) {
+		records = append(records, sarama.RecordHeader{
+			Key:   []byte(k),
+			Value: []byte(headers[k]),
+		})
+	}
+	return records
+}
+
 // buildValueEncoder chooses the best Kafka encoder based on the type of input.
 // - If input is []byte → use ByteEncoder (efficient for JSON/raw data).
 // - If input is string → use StringEncoder (efficient for plain text).
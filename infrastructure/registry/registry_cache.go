@@ -0,0 +1,69 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-pipeline/config"
+	"go-pipeline/infrastructure/cache"
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/logger"
+)
+
+type CacheRegistry struct {
+	redis   map[string]*cache.RedisDedupStore
+	traceID string
+}
+
+func NewCacheRegistry(ctx context.Context) (*CacheRegistry, error) {
+	cacheRegistry := &CacheRegistry{
+		redis:   make(map[string]*cache.RedisDedupStore),
+		traceID: config.GetTraceID(ctx),
+	}
+
+	for _, cacheCFG := range config.Get().CacheConfig {
+		switch cacheCFG.Type {
+		case "redis":
+			store := &cache.RedisDedupStore{
+				Config: &cache.RedisConfig{
+					Name:     cacheCFG.Name,
+					Port:     cacheCFG.Port,
+					Address:  cacheCFG.Address,
+					Password: cacheCFG.Password,
+					DB:       cacheCFG.DB,
+				},
+			}
+			if err := store.Connect(ctx); err != nil {
+				return nil, err
+			}
+			cacheRegistry.redis[cacheCFG.Name] = store
+		default:
+			return nil, fmt.Errorf(`unknown cache configuration "%s"`, cacheCFG.Type)
+		}
+	}
+
+	return cacheRegistry, nil
+}
+
+// GetDedupStore returns the Redis dedup store, or nil when none is
+// configured.
+func (r *CacheRegistry) GetDedupStore() ports.DedupStore {
+	store, exists := r.redis["redis-dedup"]
+	if !exists {
+		return nil
+	}
+	return store
+}
+
+func (r *CacheRegistry) Close() error {
+	logger.GetLogger().Warn(&logger.Log{
+		Event:   "close cache registry",
+		TraceID: r.traceID,
+	})
+	var errs []error
+	for _, s := range r.redis {
+		errs = append(errs, s.Close())
+	}
+	return errors.Join(errs...)
+}
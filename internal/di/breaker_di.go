@@ -0,0 +1,58 @@
+package di
+
+import (
+	"errors"
+	"time"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/pkg/apperror"
+	"go-pipeline/pkg/logger"
+)
+
+const (
+	producerBreakerName      = "kafka-producer"
+	deadLetterBreakerName    = "kafka-dead-letter"
+	producerBreakerThreshold = 5
+	producerBreakerCooldown  = 30 * time.Second
+)
+
+// NewProducerBreaker builds the circuit breaker in front of the Kafka
+// producer. While Kafka is down, produces fail fast instead of waiting for
+// the producer's own timeouts and retries. State changes are logged.
+func NewProducerBreaker() *pipelines.CircuitBreaker {
+	return newKafkaBreaker(producerBreakerName)
+}
+
+// NewDeadLetterBreaker builds the circuit breaker in front of the dead
+// letter produces. It is separate from the producer breaker, so failing
+// items are still dead-lettered while the users topic trips its breaker.
+func NewDeadLetterBreaker() *pipelines.CircuitBreaker {
+	return newKafkaBreaker(deadLetterBreakerName)
+}
+
+func newKafkaBreaker(name string) *pipelines.CircuitBreaker {
+	return pipelines.NewCircuitBreaker(name, pipelines.BreakerConfig{
+		FailureThreshold: producerBreakerThreshold,
+		Cooldown:         producerBreakerCooldown,
+		// a send that may have gone through is not retried, but it still
+		// shows that Kafka is in trouble
+		IsFailure: func(err error) bool {
+			return pipelines.IsRetryable(err) || errors.Is(err, apperror.ErrInternal)
+		},
+	}).OnStateChange(logBreaker)
+}
+
+func logBreaker(name string, from, to pipelines.BreakerState) {
+	log := logger.GetLogger().Info
+	if to == pipelines.BreakerOpen {
+		log = logger.GetLogger().Warn
+	}
+	log(&logger.Log{
+		Event: "circuit breaker state change",
+		Additional: map[string]interface{}{
+			"breaker": name,
+			"from":    from.String(),
+			"to":      to.String(),
+		},
+	})
+}
@@ -0,0 +1,44 @@
+package di
+
+import (
+	"go-pipeline/config"
+	"go-pipeline/internal/pipelines"
+)
+
+// Pipeline names, as used in the pipelines config section.
+const (
+	PipelineParallel = "parallel"
+	PipelineBarrier  = "barrier"
+	PipelineShort    = "short"
+	PipelineDAG      = "dag"
+)
+
+// NewBufferPlan builds the channel buffers of a pipeline from its config
+// entry. Sizes that are not configured fall back to BuffData and BuffErr.
+func NewBufferPlan(cfg config.PipelineConfig) pipelines.BufferPlan {
+	plan := pipelines.BufferPlan{
+		Default: pipelines.Buffers{Data: config.BuffData, Err: config.BuffErr},
+		Stages:  make(map[string]pipelines.Buffers),
+	}
+	if cfg.Buffers.Data > 0 {
+		plan.Default.Data = cfg.Buffers.Data
+	}
+	if cfg.Buffers.Err > 0 {
+		plan.Default.Err = cfg.Buffers.Err
+	}
+	for _, st := range cfg.Stages {
+		if st.Buffers != (config.BufferConfig{}) {
+			plan.Stages[st.Name] = pipelines.Buffers{Data: st.Buffers.Data, Err: st.Buffers.Err}
+		}
+	}
+	return plan
+}
+
+// barrierCap returns the phase capacity of a barrier pipeline,
+// BuffBarrierCap unless configured.
+func barrierCap(cfg config.PipelineConfig) int {
+	if cfg.Buffers.Barrier > 0 {
+		return cfg.Buffers.Barrier
+	}
+	return config.BuffBarrierCap
+}
@@ -0,0 +1,17 @@
+package di
+
+import (
+	"go-pipeline/config"
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/internal/ports"
+)
+
+// NewDedupStore returns the store of the produce stages: the shared Redis store
+// when one is configured, otherwise an in-memory store that only catches
+// duplicates reaching this instance.
+func NewDedupStore(shared ports.DedupStore) ports.DedupStore {
+	if shared != nil {
+		return shared
+	}
+	return pipelines.NewMemoryDedupStore(config.DedupCapacity)
+}
@@ -0,0 +1,46 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-pipeline/config"
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/pkg/logger"
+)
+
+// NewInterceptors returns the interceptors every pipeline runs with.
+func NewInterceptors() []pipelines.Interceptor {
+	return []pipelines.Interceptor{LoggingInterceptor()}
+}
+
+// LoggingInterceptor logs every item processed by an item stage or a
+// short-circuit step with its stage and duration under the trace ID of its
+// context: panics at error level with their stack, other failures at warn
+// level and the rest at debug level.
+func LoggingInterceptor() pipelines.Interceptor {
+	return pipelines.Hooks(nil, logItem)
+}
+
+func logItem(ctx context.Context, call pipelines.StageCall, d time.Duration, err error) {
+	additional := map[string]interface{}{
+		"stage":    call.Stage,
+		"duration": d.String(),
+	}
+	log := logger.GetLogger().Debug
+	var pe *pipelines.PanicError
+	switch {
+	case errors.As(err, &pe):
+		log = logger.GetLogger().Error
+		additional["stack"] = string(pe.Stack)
+	case err != nil:
+		log = logger.GetLogger().Warn
+	}
+	log(&logger.Log{
+		Event:      "stage item",
+		Error:      err,
+		TraceID:    config.GetTraceID(ctx),
+		Additional: additional,
+	})
+}
@@ -0,0 +1,394 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go-pipeline/config"
+	"go-pipeline/internal/model"
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/internal/ports"
+	"go-pipeline/internal/stages"
+	"go-pipeline/pkg/apperror"
+)
+
+// Runner kinds of declared pipelines.
+const (
+	RunnerChain   = "chain"
+	RunnerBarrier = "barrier"
+	RunnerShort   = "short"
+)
+
+// Error policies of barrier pipelines.
+const (
+	PolicyContinue  = "continue"
+	PolicyFailFast  = "fail_fast"
+	PolicyThreshold = "threshold" // aborts above max_errors or max_ratio
+)
+
+// builtinRunners holds the runner of every built-in pipeline the config may
+// redeclare. The dag pipeline can only be tuned.
+var builtinRunners = map[string]string{
+	PipelineParallel: RunnerChain,
+	PipelineBarrier:  RunnerBarrier,
+	PipelineShort:    RunnerShort,
+}
+
+// Trigger binds a declared pipeline to the HTTP route and/or Kafka topic
+// that starts it.
+type Trigger struct {
+	Pipeline string
+	Route    string
+	Topic    string
+	Run      ports.BatchPipeLine[model.UserData]
+}
+
+// declaredPipeline is a pipeline built from its definition; exactly one of
+// the runners is set.
+type declaredPipeline struct {
+	def     config.PipelineConfig
+	chain   *pipelines.Runner[model.UserData]
+	barrier *pipelines.RunnerBarrier[model.UserData]
+	short   *pipelines.RunnerShortCircuit[model.UserData]
+}
+
+func (p declaredPipeline) batch() ports.BatchPipeLine[model.UserData] {
+	switch {
+	case p.chain != nil:
+		return p.chain
+	case p.barrier != nil:
+		return p.barrier
+	default:
+		return p.short
+	}
+}
+
+// builtinPipelines returns the definitions of the built-in pipelines.
+func builtinPipelines() []config.PipelineConfig {
+	registry := []config.StageConfig{
+		{Name: "validation_registry", Workers: config.ValidationWorkers},
+		{Name: "store_registry", Workers: config.StoreWorkers},
+		{Name: "produce_throttle"},
+		{Name: "produce-registry", Workers: config.ProduceWorkers},
+	}
+	return []config.PipelineConfig{
+		{Name: PipelineParallel, Runner: RunnerChain, Stages: registry},
+		{
+			Name:        PipelineBarrier,
+			Runner:      RunnerBarrier,
+			ErrorPolicy: PolicyFailFast,
+			Stages:      registry,
+		},
+		{
+			Name:   PipelineShort,
+			Runner: RunnerShort,
+			Stages: []config.StageConfig{
+				{Name: "validation_registry"},
+				{Name: "transform"},
+				{Name: "sink"},
+			},
+		},
+	}
+}
+
+// pipelineDefinitions returns the built-in definitions, each replaced by the
+// config entry of the same name if that entry declares a runner, or tuned by
+// it if it does not, followed by the other declared pipelines in config
+// order.
+func pipelineDefinitions(cfgs []config.PipelineConfig) []config.PipelineConfig {
+	defs := builtinPipelines()
+	for _, cfg := range cfgs {
+		if tuneOnly(cfg.Name) {
+			continue
+		}
+		i := slices.IndexFunc(defs, func(d config.PipelineConfig) bool {
+			return d.Name == cfg.Name
+		})
+		switch {
+		case cfg.Runner == "" && i >= 0:
+			defs[i] = tune(defs[i], cfg)
+		case cfg.Runner == "":
+		case i < 0:
+			defs = append(defs, cfg)
+		default:
+			defs[i] = cfg
+		}
+	}
+	return defs
+}
+
+// tune applies the buffers of cfg, an entry without a runner, to def.
+func tune(def, cfg config.PipelineConfig) config.PipelineConfig {
+	def.Buffers = cfg.Buffers
+	def.Stages = slices.Clone(def.Stages)
+	for i, sc := range def.Stages {
+		j := slices.IndexFunc(cfg.Stages, func(c config.StageConfig) bool {
+			return c.Name == sc.Name
+		})
+		if j >= 0 {
+			def.Stages[i].Buffers = cfg.Stages[j].Buffers
+		}
+	}
+	return def
+}
+
+// pipelineConfig returns the entry of cfgs with the given name, or an empty
+// entry of that name.
+func pipelineConfig(cfgs []config.PipelineConfig, name string) config.PipelineConfig {
+	i := slices.IndexFunc(cfgs, func(c config.PipelineConfig) bool { return c.Name == name })
+	if i < 0 {
+		return config.PipelineConfig{Name: name}
+	}
+	return cfgs[i]
+}
+
+// buildPipelines validates the pipelines config and builds every defined
+// pipeline together with the triggers of the declared ones; every runner
+// gets ics. All problems are reported at once, each wrapping
+// apperror.ErrInvalidInput.
+func buildPipelines(
+	st *Stages,
+	cfgs []config.PipelineConfig,
+	ics []pipelines.Interceptor,
+) (map[string]declaredPipeline, []Trigger, error) {
+	defs := pipelineDefinitions(cfgs)
+	if err := validatePipelines(cfgs, defs); err != nil {
+		return nil, nil, err
+	}
+
+	built := make(map[string]declaredPipeline, len(defs))
+	var triggers []Trigger
+	var errs []error
+	for _, def := range defs {
+		p, err := buildPipeline(st, def, ics)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		built[def.Name] = p
+		if def.Trigger != (config.TriggerConfig{}) {
+			triggers = append(triggers, Trigger{
+				Pipeline: def.Name,
+				Route:    def.Trigger.Route,
+				Topic:    def.Trigger.Topic,
+				Run:      p.batch(),
+			})
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, nil, err
+	}
+	return built, triggers, nil
+}
+
+// buildPipeline builds the stages of def from the stage registry and puts
+// them into its runner.
+func buildPipeline(
+	st *Stages,
+	def config.PipelineConfig,
+	ics []pipelines.Interceptor,
+) (declaredPipeline, error) {
+	if def.Runner == RunnerShort {
+		var steps []pipelines.Step[model.UserData]
+		for _, sc := range enabled(def.Stages) {
+			fn, err := stages.Registry.Fn(sc.Name, st.deps, sc.Options)
+			if err != nil {
+				return declaredPipeline{}, fmt.Errorf("pipeline %q: %w", def.Name, err)
+			}
+			steps = append(steps, pipelines.Step[model.UserData]{Name: sc.Name, Fn: fn})
+		}
+		short := pipelines.NewRunnerShortCircuitSteps(steps...).
+			WithDeadLetter(st.DeadLetter).
+			WithInterceptors(ics...).
+			WithTimeout(pipelineTimeout)
+		return declaredPipeline{def: def, short: short}, nil
+	}
+
+	plan := NewBufferPlan(def)
+	var chain []ports.Stage[model.UserData]
+	for _, sc := range enabled(def.Stages) {
+		stage, err := stages.Registry.Stage(sc.Name, st.deps, sc.Options)
+		if err != nil {
+			return declaredPipeline{}, fmt.Errorf("pipeline %q: %w", def.Name, err)
+		}
+		if sc.Workers > 0 {
+			stage = pipelines.NewWorkerStage(stage, sc.Workers)
+		}
+		chain = append(chain, stage)
+	}
+
+	if def.Runner == RunnerBarrier {
+		barrier := pipelines.NewRunnerBarrier(barrierCap(def), chain...).
+			WithDeadLetter(st.DeadLetter).
+			WithErrorPolicy(errorPolicy(def)).
+			WithBuffers(plan).
+			WithInterceptors(ics...).
+			WithTimeout(pipelineTimeout)
+		return declaredPipeline{def: def, barrier: barrier}, nil
+	}
+
+	runner := pipelines.NewRunner(chain...).
+		WithDeadLetter(st.DeadLetter).
+		WithBuffers(plan).
+		WithInterceptors(ics...).
+		WithTimeout(pipelineTimeout)
+	return declaredPipeline{def: def, chain: runner}, nil
+}
+
+// errorPolicy returns the error policy of a barrier pipeline.
+func errorPolicy(def config.PipelineConfig) pipelines.ErrorPolicy {
+	switch def.ErrorPolicy {
+	case PolicyFailFast:
+		return pipelines.FailFast()
+	case PolicyThreshold:
+		return pipelines.AbortAbove(def.MaxErrors, def.MaxRatio)
+	default:
+		return pipelines.ContinueOnError()
+	}
+}
+
+func enabled(stages []config.StageConfig) []config.StageConfig {
+	var out []config.StageConfig
+	for _, sc := range stages {
+		if !sc.Disabled {
+			out = append(out, sc)
+		}
+	}
+	return out
+}
+
+// validatePipelines checks the raw config entries for duplicates and
+// reserved names, then every definition and the triggers.
+func validatePipelines(cfgs, defs []config.PipelineConfig) error {
+	var errs []error
+	seen := make(map[string]bool, len(cfgs))
+	for _, cfg := range cfgs {
+		if seen[cfg.Name] {
+			errs = append(errs, invalidPipeline(cfg.Name, "defined more than once"))
+		}
+		seen[cfg.Name] = true
+		if cfg.Runner != "" && tuneOnly(cfg.Name) {
+			errs = append(errs, invalidPipeline(cfg.Name, "only its buffers can be set"))
+		}
+	}
+	for _, def := range defs {
+		errs = append(errs, validatePipeline(def)...)
+	}
+	errs = append(errs, validateTriggers(defs)...)
+	return errors.Join(errs...)
+}
+
+// tuneOnly reports whether the named built-in pipeline cannot be redeclared.
+func tuneOnly(name string) bool {
+	return name == PipelineDAG
+}
+
+func validatePipeline(def config.PipelineConfig) []error {
+	switch def.Runner {
+	case RunnerChain, RunnerBarrier, RunnerShort:
+	default:
+		return []error{invalidPipeline(def.Name, "unknown runner %q", def.Runner)}
+	}
+
+	var errs []error
+	if want, ok := builtinRunners[def.Name]; ok && def.Runner != want {
+		errs = append(errs, invalidPipeline(def.Name, "built-in, runner must be %q", want))
+	}
+	errs = append(errs, validateErrorPolicy(def)...)
+	if len(enabled(def.Stages)) == 0 {
+		errs = append(errs, invalidPipeline(def.Name, "no enabled stages"))
+	}
+	for _, sc := range def.Stages {
+		errs = append(errs, validateStage(def, sc)...)
+	}
+	return errs
+}
+
+func validateErrorPolicy(def config.PipelineConfig) []error {
+	var errs []error
+	if def.ErrorPolicy != "" && def.Runner != RunnerBarrier {
+		errs = append(errs, invalidPipeline(def.Name, "error_policy needs a barrier runner"))
+	}
+	switch def.ErrorPolicy {
+	case "", PolicyContinue, PolicyFailFast:
+		if def.MaxErrors != 0 || def.MaxRatio != 0 {
+			errs = append(errs, invalidPipeline(def.Name,
+				"max_errors and max_ratio need the %s error_policy", PolicyThreshold))
+		}
+	case PolicyThreshold:
+		if def.MaxErrors <= 0 && def.MaxRatio <= 0 {
+			errs = append(errs, invalidPipeline(def.Name,
+				"%s error_policy needs max_errors or max_ratio", PolicyThreshold))
+		}
+	default:
+		errs = append(errs, invalidPipeline(def.Name, "unknown error_policy %q", def.ErrorPolicy))
+	}
+	if def.MaxErrors < 0 {
+		errs = append(errs, invalidPipeline(def.Name, "negative max_errors"))
+	}
+	if def.MaxRatio < 0 || def.MaxRatio > 1 {
+		errs = append(errs, invalidPipeline(def.Name, "max_ratio must be between 0 and 1"))
+	}
+	return errs
+}
+
+func validateStage(def config.PipelineConfig, sc config.StageConfig) []error {
+	f, ok := stages.Registry.Lookup(sc.Name)
+	if !ok {
+		return []error{invalidPipeline(def.Name, "unknown stage %q", sc.Name)}
+	}
+
+	var errs []error
+	if sc.Workers < 0 {
+		errs = append(errs, invalidPipeline(def.Name, "stage %q: negative workers", sc.Name))
+	}
+	if _, err := pipelines.ParseOptions(f.Options, sc.Options); err != nil {
+		errs = append(errs, fmt.Errorf("pipeline %q: stage %q: %w", def.Name, sc.Name, err))
+	}
+	return errs
+}
+
+// validateTriggers checks that routes and topics start one pipeline each and
+// that every topic is consumed.
+func validateTriggers(defs []config.PipelineConfig) []error {
+	consumed := make(map[string]bool)
+	for _, mq := range config.Get().MQConfig {
+		if mq.Type == "consumer" {
+			for _, topic := range mq.Topics {
+				consumed[topic] = true
+			}
+		}
+	}
+
+	var errs []error
+	routes := make(map[string]string)
+	topics := make(map[string]string)
+	for _, def := range defs {
+		if r := def.Trigger.Route; r != "" {
+			if !strings.HasPrefix(r, "/") {
+				errs = append(errs, invalidPipeline(def.Name, "route %q must start with /", r))
+			}
+			if other, ok := routes[r]; ok {
+				errs = append(errs, invalidPipeline(def.Name, "route %q taken by %q", r, other))
+			}
+			routes[r] = def.Name
+		}
+		if t := def.Trigger.Topic; t != "" {
+			if !consumed[t] {
+				errs = append(errs, invalidPipeline(def.Name, "topic %q is not consumed", t))
+			}
+			if other, ok := topics[t]; ok {
+				errs = append(errs, invalidPipeline(def.Name, "topic %q taken by %q", t, other))
+			}
+			topics[t] = def.Name
+		}
+	}
+	return errs
+}
+
+func invalidPipeline(name, format string, args ...any) error {
+	return fmt.Errorf("%w: pipeline %q: %s",
+		apperror.ErrInvalidInput, name, fmt.Sprintf(format, args...))
+}
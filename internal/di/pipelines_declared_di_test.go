@@ -0,0 +1,310 @@
+package di
+
+import (
+	"context"
+	"testing"
+
+	"go-pipeline/config"
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nopProducer accepts every message.
+type nopProducer struct{}
+
+func (nopProducer) Connect(context.Context) error                      { return nil }
+func (nopProducer) Close() error                                       { return nil }
+func (nopProducer) Produce(context.Context, string, interface{}) error { return nil }
+
+func stage(name string) []config.StageConfig {
+	return []config.StageConfig{{Name: name}}
+}
+
+func TestBuiltinPipelines(t *testing.T) {
+	want := map[string][]string{
+		PipelineParallel: {"validation_registry", "store_registry", "produce_throttle",
+			"produce-registry"},
+		PipelineBarrier: {"validation_registry", "store_registry", "produce_throttle",
+			"produce-registry"},
+		PipelineShort: {"validation_registry", "transform", "sink"},
+	}
+
+	got := make(map[string][]string)
+	for _, def := range builtinPipelines() {
+		for _, sc := range def.Stages {
+			got[def.Name] = append(got[def.Name], sc.Name)
+		}
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestBuildPipelines(t *testing.T) {
+	signup := config.PipelineConfig{
+		Name:    "signup",
+		Runner:  RunnerChain,
+		Trigger: config.TriggerConfig{Route: "/signup"},
+		Stages:  stage("validation_registry"),
+	}
+
+	tests := []struct {
+		name     string
+		cfgs     []config.PipelineConfig
+		wantErr  string // substring of the error, empty when valid
+		triggers int
+	}{
+		{name: "Builtin"},
+		{name: "Declared", cfgs: []config.PipelineConfig{signup}, triggers: 1},
+		{
+			name: "BuffersOnly",
+			cfgs: []config.PipelineConfig{
+				{Name: PipelineDAG, Buffers: config.BufferConfig{Data: 8}},
+			},
+		},
+		{
+			name: "UnknownRunner",
+			cfgs: []config.PipelineConfig{
+				{Name: "p", Runner: "fanout", Stages: stage("transform")},
+			},
+			wantErr: `unknown runner "fanout"`,
+		},
+		{
+			name: "BuiltinRunnerChanged",
+			cfgs: []config.PipelineConfig{
+				{Name: PipelineParallel, Runner: RunnerShort, Stages: stage("transform")},
+			},
+			wantErr: `runner must be "chain"`,
+		},
+		{
+			name: "TuneOnlyRedeclared",
+			cfgs: []config.PipelineConfig{
+				{Name: PipelineDAG, Runner: RunnerChain, Stages: stage("transform")},
+			},
+			wantErr: "only its buffers can be set",
+		},
+		{
+			name: "UnknownStage",
+			cfgs: []config.PipelineConfig{
+				{Name: "p", Runner: RunnerChain, Stages: stage("nope")},
+			},
+			wantErr: `unknown stage "nope"`,
+		},
+		{
+			name:    "Duplicate",
+			cfgs:    []config.PipelineConfig{signup, signup},
+			wantErr: "defined more than once",
+		},
+		{
+			name: "DuplicateRoute",
+			cfgs: []config.PipelineConfig{signup, {
+				Name:    "other",
+				Runner:  RunnerShort,
+				Trigger: signup.Trigger,
+				Stages:  stage("transform"),
+			}},
+			wantErr: `route "/signup" taken by "signup"`,
+		},
+		{
+			name: "RouteWithoutSlash",
+			cfgs: []config.PipelineConfig{{
+				Name:    "p",
+				Runner:  RunnerChain,
+				Trigger: config.TriggerConfig{Route: "signup"},
+				Stages:  stage("transform"),
+			}},
+			wantErr: "must start with /",
+		},
+		{
+			name: "TopicNotConsumed",
+			cfgs: []config.PipelineConfig{{
+				Name:    "p",
+				Runner:  RunnerChain,
+				Trigger: config.TriggerConfig{Topic: "signups"},
+				Stages:  stage("transform"),
+			}},
+			wantErr: `topic "signups" is not consumed`,
+		},
+		{
+			name: "UnknownOption",
+			cfgs: []config.PipelineConfig{{
+				Name:   "p",
+				Runner: RunnerChain,
+				Stages: []config.StageConfig{
+					{Name: "produce-registry", Options: map[string]string{"speed": "fast"}},
+				},
+			}},
+			wantErr: `unknown option "speed"`,
+		},
+		{
+			name: "NegativeWorkers",
+			cfgs: []config.PipelineConfig{{
+				Name:   "p",
+				Runner: RunnerChain,
+				Stages: []config.StageConfig{{Name: "transform", Workers: -1}},
+			}},
+			wantErr: "negative workers",
+		},
+		{
+			name: "ErrorPolicyWithoutBarrier",
+			cfgs: []config.PipelineConfig{{
+				Name:        "p",
+				Runner:      RunnerChain,
+				ErrorPolicy: PolicyFailFast,
+				Stages:      stage("transform"),
+			}},
+			wantErr: "error_policy needs a barrier runner",
+		},
+		{
+			name: "Threshold",
+			cfgs: []config.PipelineConfig{{
+				Name:        "p",
+				Runner:      RunnerBarrier,
+				ErrorPolicy: PolicyThreshold,
+				MaxErrors:   3,
+				MaxRatio:    0.5,
+				Stages:      stage("transform"),
+			}},
+		},
+		{
+			name: "ThresholdWithoutLimits",
+			cfgs: []config.PipelineConfig{{
+				Name:        "p",
+				Runner:      RunnerBarrier,
+				ErrorPolicy: PolicyThreshold,
+				Stages:      stage("transform"),
+			}},
+			wantErr: "threshold error_policy needs max_errors or max_ratio",
+		},
+		{
+			name: "LimitsWithoutThreshold",
+			cfgs: []config.PipelineConfig{{
+				Name:        "p",
+				Runner:      RunnerBarrier,
+				ErrorPolicy: PolicyFailFast,
+				MaxErrors:   3,
+				Stages:      stage("transform"),
+			}},
+			wantErr: "max_errors and max_ratio need the threshold error_policy",
+		},
+		{
+			name: "MaxRatioAboveOne",
+			cfgs: []config.PipelineConfig{{
+				Name:        "p",
+				Runner:      RunnerBarrier,
+				ErrorPolicy: PolicyThreshold,
+				MaxRatio:    1.5,
+				Stages:      stage("transform"),
+			}},
+			wantErr: "max_ratio must be between 0 and 1",
+		},
+		{
+			name: "UnknownErrorPolicy",
+			cfgs: []config.PipelineConfig{{
+				Name:        "p",
+				Runner:      RunnerBarrier,
+				ErrorPolicy: "retry",
+				Stages:      stage("transform"),
+			}},
+			wantErr: `unknown error_policy "retry"`,
+		},
+		{
+			name: "NoEnabledStages",
+			cfgs: []config.PipelineConfig{{
+				Name:   "p",
+				Runner: RunnerChain,
+				Stages: []config.StageConfig{{Name: "transform", Disabled: true}},
+			}},
+			wantErr: "no enabled stages",
+		},
+		{
+			name: "InvalidStageOption",
+			cfgs: []config.PipelineConfig{{
+				Name:   "p",
+				Runner: RunnerChain,
+				Stages: []config.StageConfig{
+					{Name: "produce-registry", Options: map[string]string{"dedup": "once"}},
+				},
+			}},
+			wantErr: `option "dedup" must be skip, reject or off`,
+		},
+	}
+
+	st, err := NewStagesContainer(nopProducer{}, nil)
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			built, triggers, err := buildPipelines(st, tt.cfgs, NewInterceptors())
+
+			if tt.wantErr != "" {
+				require.ErrorIs(t, err, apperror.ErrInvalidInput)
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, triggers, tt.triggers)
+			for _, name := range []string{PipelineParallel, PipelineBarrier, PipelineShort} {
+				assert.Contains(t, built, name)
+			}
+		})
+	}
+}
+
+func TestPipelineBuffers(t *testing.T) {
+	tuned := config.BufferConfig{Data: 8, Err: 4, Barrier: 32}
+	cfgs := []config.PipelineConfig{
+		{
+			Name:    PipelineBarrier,
+			Buffers: tuned,
+			Stages: []config.StageConfig{
+				{Name: "store_registry", Buffers: config.BufferConfig{Data: 2}},
+			},
+		},
+		{Name: "p", Runner: RunnerChain, Buffers: tuned, Stages: stage("transform")},
+	}
+	defs := pipelineDefinitions(cfgs)
+
+	tests := []struct {
+		name   string
+		want   pipelines.BufferPlan
+		barCap int
+	}{
+		{
+			name: PipelineParallel,
+			want: pipelines.BufferPlan{
+				Default: pipelines.Buffers{Data: config.BuffData, Err: config.BuffErr},
+				Stages:  map[string]pipelines.Buffers{},
+			},
+			barCap: config.BuffBarrierCap,
+		},
+		{
+			name: PipelineBarrier,
+			want: pipelines.BufferPlan{
+				Default: pipelines.Buffers{Data: 8, Err: 4},
+				Stages:  map[string]pipelines.Buffers{"store_registry": {Data: 2}},
+			},
+			barCap: 32,
+		},
+		{
+			name: "p",
+			want: pipelines.BufferPlan{
+				Default: pipelines.Buffers{Data: 8, Err: 4},
+				Stages:  map[string]pipelines.Buffers{},
+			},
+			barCap: 32,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := pipelineConfig(defs, tt.name)
+			assert.Equal(t, tt.want, NewBufferPlan(def))
+			assert.Equal(t, tt.barCap, barrierCap(def))
+		})
+	}
+
+	// tuning the barrier pipeline leaves the stages it shares untouched
+	assert.Empty(t, pipelineConfig(defs, PipelineParallel).Stages[1].Buffers)
+}
@@ -1,11 +1,17 @@
 package di
 
 import (
+	"time"
+
 	"go-pipeline/config"
 	"go-pipeline/internal/model"
 	"go-pipeline/internal/pipelines"
 )
 
+// pipelineTimeout bounds a whole pipeline run, so a request never hangs on a
+// stuck stage.
+const pipelineTimeout = 30 * time.Second
+
 type Pipelines struct {
 	// 1) parallel (channels)
 	Parallel *pipelines.Runner[model.UserData]
@@ -13,31 +19,47 @@ type Pipelines struct {
 	Barrier *pipelines.RunnerBarrier[model.UserData]
 	// 3) fn (short)
 	Short *pipelines.RunnerShortCircuit[model.UserData]
+	// 4) dag (store and produce side by side)
+	DAG *pipelines.RunnerDAG[model.UserData]
+	// Triggers start the pipelines declared in the config
+	Triggers []Trigger
 }
 
-func NewPipelines(st *Stages) *Pipelines {
-	registry := pipelines.NewRunner[model.UserData](
-		st.Registry.Validation,
-		st.Registry.Store,
-		st.Registry.Produce,
-	)
+// NewPipelines builds the pipelines. Parallel, barrier and short follow
+// their definitions in the pipelines config section, falling back to the
+// built-in ones; invalid definitions fail the startup. Every pipeline runs
+// with the interceptors of NewInterceptors.
+func NewPipelines(st *Stages) (*Pipelines, error) {
+	ics := NewInterceptors()
+	cfgs := config.Get().Pipelines
+	declared, triggers, err := buildPipelines(st, cfgs, ics)
+	if err != nil {
+		return nil, err
+	}
 
-	b := pipelines.NewRunnerBarrier[model.UserData](
-		config.BuffBarrierCap,
-		st.Registry.Validation,
-		st.Registry.Store,
-		st.Registry.Produce,
-	)
+	validation := pipelines.NewWorkerStage(st.Registry.Validation, config.ValidationWorkers)
+	store := pipelines.NewWorkerStage(st.Registry.Store, config.StoreWorkers)
+	produce := pipelines.NewWorkerStage(st.Registry.Produce, config.ProduceWorkers)
 
-	rfn := pipelines.NewRunnerShortCircuit[model.UserData](
-		st.ShortCircuits.Validation,
-		st.ShortCircuits.Transform,
-		st.ShortCircuits.Sink,
+	// store is a side branch: every user comes out of produce only
+	dag, err := pipelines.NewRunnerDAG(
+		pipelines.Node(validation),
+		pipelines.DiscardNode(store, validation.Name()),
+		pipelines.Node(st.Registry.Throttle, validation.Name()),
+		pipelines.Node(produce, st.Registry.Throttle.Name()),
 	)
+	if err != nil {
+		return nil, err
+	}
 
 	return &Pipelines{
-		Parallel: registry,
-		Barrier:  b,
-		Short:    rfn,
-	}
+		Parallel: declared[PipelineParallel].chain,
+		Barrier:  declared[PipelineBarrier].barrier,
+		Short:    declared[PipelineShort].short,
+		DAG: dag.WithDeadLetter(st.DeadLetter).
+			WithBuffers(NewBufferPlan(pipelineConfig(cfgs, PipelineDAG))).
+			WithInterceptors(ics...).
+			WithTimeout(pipelineTimeout),
+		Triggers: triggers,
+	}, nil
 }
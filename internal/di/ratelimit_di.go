@@ -0,0 +1,17 @@
+package di
+
+import (
+	"go-pipeline/config"
+	"go-pipeline/internal/model"
+	"go-pipeline/internal/pipelines"
+)
+
+// NewProduceLimiter builds the limiter shared by every stage that writes to
+// Kafka, so bursts on the HTTP endpoints are smoothed before they reach the
+// broker. Limited items wait for a token instead of failing.
+func NewProduceLimiter() *pipelines.RateLimiter[model.UserData] {
+	return pipelines.NewRateLimiter[model.UserData](pipelines.RateLimit{
+		Rate:  config.ProduceRate,
+		Burst: config.ProduceBurst,
+	})
+}
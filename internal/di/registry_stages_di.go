@@ -1,6 +1,8 @@
 package di
 
 import (
+	"errors"
+
 	"go-pipeline/internal/model"
 	"go-pipeline/internal/ports"
 	"go-pipeline/internal/stages"
@@ -9,17 +11,27 @@ import (
 type RegistryStages struct {
 	Validation ports.Stage[model.UserData]
 	Store      ports.Stage[model.UserData]
+	Throttle   ports.Stage[model.UserData]
 	Produce    ports.Stage[model.UserData]
 }
 
-func NewRegistryStages(p ports.MessageQueueProducer) *RegistryStages {
-	validation := stages.NewValidationRegistryStage()
-	store := stages.NewStoreRegistryStage()
-	producer := stages.NewProduceRegistryStage(p)
-
-	return &RegistryStages{
-		Validation: validation,
-		Store:      store,
-		Produce:    producer,
+// NewRegistryStages builds the registry stages from the stage registry with
+// their default options.
+func NewRegistryStages(deps stages.Deps) (*RegistryStages, error) {
+	var errs []error
+	build := func(name string) ports.Stage[model.UserData] {
+		stage, err := stages.Registry.Stage(name, deps, nil)
+		errs = append(errs, err)
+		return stage
+	}
+	rs := &RegistryStages{
+		Validation: build("validation_registry"),
+		Store:      build("store_registry"),
+		Throttle:   build("produce_throttle"),
+		Produce:    build("produce-registry"),
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
 	}
+	return rs, nil
 }
@@ -0,0 +1,41 @@
+package di
+
+import (
+	"context"
+	"time"
+
+	"go-pipeline/config"
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/pkg/logger"
+)
+
+const (
+	retryMaxDelay = 5 * time.Second
+	retryJitter   = 0.2
+)
+
+// NewRetryPolicy builds the stage retry policy from the worker pool config.
+// Every retry is logged with the trace ID of the item's context.
+func NewRetryPolicy() pipelines.RetryPolicy {
+	cfg := config.Get().WorkerPoolConfig
+	return pipelines.RetryPolicy{
+		MaxAttempts: cfg.RetryMax + 1,
+		BaseDelay:   time.Duration(cfg.RetryDelay) * time.Millisecond,
+		MaxDelay:    retryMaxDelay,
+		Jitter:      retryJitter,
+		OnRetry:     logRetry,
+	}
+}
+
+func logRetry(ctx context.Context, stage string, attempt int, err error, delay time.Duration) {
+	logger.GetLogger().Warn(&logger.Log{
+		Event:   "retry stage",
+		Error:   err,
+		TraceID: config.GetTraceID(ctx),
+		Additional: map[string]interface{}{
+			"stage":   stage,
+			"attempt": attempt,
+			"delay":   delay.String(),
+		},
+	})
+}
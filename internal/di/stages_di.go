@@ -1,20 +1,53 @@
 package di
 
 import (
+	"go-pipeline/config"
+	"go-pipeline/internal/model"
+	"go-pipeline/internal/pipelines"
 	"go-pipeline/internal/ports"
+	"go-pipeline/internal/stages"
 )
 
 type Stages struct {
-	Registry      *RegistryStages
-	ShortCircuits *ShortCircuits
+	Registry          *RegistryStages
+	DeadLetter        ports.DeadLetterSink[model.UserData]
+	RawDeadLetter     ports.DeadLetterSink[string] // consumed messages that are no user
+	ProducerBreaker   *pipelines.CircuitBreaker
+	DeadLetterBreaker *pipelines.CircuitBreaker
+
+	// deps builds further stages for the pipelines declared in the config
+	deps stages.Deps
 }
 
-func NewStagesContainer(p ports.MessageQueueProducer) *Stages {
-	registry := NewRegistryStages(p)
-	sc := NewShortCircuits(p)
+// NewStagesContainer builds the stages on top of the Kafka producer and the
+// shared dedup store, which may be nil.
+func NewStagesContainer(
+	kafka ports.MessageQueueProducer,
+	dedup ports.DedupStore,
+) (*Stages, error) {
+	// produces and dead letters go through breakers of their own
+	breaker := NewProducerBreaker()
+	dlqBreaker := NewDeadLetterBreaker()
+	deps := stages.Deps{
+		Producer: pipelines.NewBreakerProducer(kafka, breaker),
+		Retry:    NewRetryPolicy(),
+		Limiter:  NewProduceLimiter(),
+		Dedup:    NewDedupStore(dedup),
+	}
+	registry, err := NewRegistryStages(deps)
+	if err != nil {
+		return nil, err
+	}
+	dlqProducer := pipelines.NewBreakerProducer(kafka, dlqBreaker)
+	dlq := pipelines.NewMQDeadLetterSink[model.UserData](dlqProducer, config.DeadLetterTopic)
+	rawDLQ := pipelines.NewMQDeadLetterSink[string](dlqProducer, config.DeadLetterTopic)
 
 	return &Stages{
-		Registry:      registry,
-		ShortCircuits: sc,
-	}
+		Registry:          registry,
+		DeadLetter:        dlq,
+		RawDeadLetter:     rawDLQ,
+		ProducerBreaker:   breaker,
+		DeadLetterBreaker: dlqBreaker,
+		deps:              deps,
+	}, nil
 }
@@ -0,0 +1,292 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+)
+
+// ErrCircuitOpen is reported for calls rejected by an open circuit breaker.
+// It is always wrapped together with apperror.ErrUnavailable.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets every call through and counts failures.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects every call until the cooldown is over.
+	BreakerOpen
+	// BreakerHalfOpen lets a few trial calls through to probe the dependency.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// BreakerConfig configures a CircuitBreaker.
+type BreakerConfig struct {
+	FailureThreshold int           // consecutive failures that open the circuit
+	Cooldown         time.Duration // time the circuit stays open
+	HalfOpenCalls    int           // successful trial calls that close it again, 1 when zero
+
+	// IsFailure classifies errors, IsRetryable is used when nil. Other errors
+	// (e.g. invalid input) show that the dependency answered and count as
+	// success.
+	IsFailure func(err error) bool
+}
+
+// CircuitBreaker stops calling a failing dependency for a while, so callers
+// fail fast with apperror.ErrUnavailable instead of waiting for timeouts.
+//
+// After FailureThreshold consecutive failures the circuit opens. Once the
+// cooldown is over it becomes half-open and lets HalfOpenCalls trial calls
+// through: if they all succeed it closes, a single failure opens it again.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	name      string
+	cfg       BreakerConfig
+	clock     Clock
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	trials    int
+	successes int
+	onChange  []func(name string, from, to BreakerState)
+}
+
+// NewCircuitBreaker creates a closed circuit breaker.
+func NewCircuitBreaker(name string, cfg BreakerConfig) *CircuitBreaker {
+	cfg.FailureThreshold = max(cfg.FailureThreshold, 1)
+	cfg.HalfOpenCalls = max(cfg.HalfOpenCalls, 1)
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = IsRetryable
+	}
+	return &CircuitBreaker{name: name, cfg: cfg, clock: SystemClock}
+}
+
+// WithClock replaces the SystemClock.
+func (b *CircuitBreaker) WithClock(c Clock) *CircuitBreaker {
+	b.clock = c
+	return b
+}
+
+// OnStateChange registers fn to be called after every state change.
+func (b *CircuitBreaker) OnStateChange(
+	fn func(name string, from, to BreakerState),
+) *CircuitBreaker {
+	b.onChange = append(b.onChange, fn)
+	return b
+}
+
+func (b *CircuitBreaker) Name() string { return b.name }
+
+// State returns the current state, moving from open to half-open if the
+// cooldown is over.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	from := b.state
+	b.cool()
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+	return to
+}
+
+// Check implements ports.HealthChecker: an open circuit is unhealthy.
+func (b *CircuitBreaker) Check(context.Context) error {
+	if b.State() == BreakerOpen {
+		return b.openErr()
+	}
+	return nil
+}
+
+// Do calls fn unless the circuit is open and records the outcome. A panic in
+// fn is recorded as a failure before it goes on up the stack.
+func (b *CircuitBreaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	failed := true
+	defer func() { b.record(failed) }()
+	err := fn(ctx)
+	failed = err != nil && b.cfg.IsFailure(err)
+	return err
+}
+
+// allow reserves a call, or rejects it while the circuit is open or all
+// half-open trials are taken.
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	from := b.state
+	b.cool()
+	to := b.state
+	rejected := to == BreakerOpen || (to == BreakerHalfOpen && b.trials >= b.cfg.HalfOpenCalls)
+	if to == BreakerHalfOpen && !rejected {
+		b.trials++
+	}
+	b.mu.Unlock()
+	b.notify(from, to)
+
+	if rejected {
+		return b.openErr()
+	}
+	return nil
+}
+
+// record updates the state with the outcome of a call.
+func (b *CircuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	from := b.state
+	switch {
+	case b.state == BreakerHalfOpen && failed:
+		b.open()
+	case b.state == BreakerHalfOpen:
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenCalls {
+			b.state, b.failures = BreakerClosed, 0
+		}
+	case failed:
+		b.failures++
+		if b.failures >= b.cfg.FailureThreshold {
+			b.open()
+		}
+	default:
+		b.failures = 0
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+// open opens the circuit. It must be called with mu held.
+func (b *CircuitBreaker) open() {
+	b.state = BreakerOpen
+	b.openedAt = b.clock.Now()
+	b.failures, b.trials, b.successes = 0, 0, 0
+}
+
+// cool moves an open circuit to half-open once the cooldown is over. It must
+// be called with mu held.
+func (b *CircuitBreaker) cool() {
+	if b.state == BreakerOpen && !b.clock.Now().Before(b.openedAt.Add(b.cfg.Cooldown)) {
+		b.state = BreakerHalfOpen
+		b.trials, b.successes = 0, 0
+	}
+}
+
+func (b *CircuitBreaker) notify(from, to BreakerState) {
+	if from == to {
+		return
+	}
+	for _, fn := range b.onChange {
+		fn(b.name, from, to)
+	}
+}
+
+func (b *CircuitBreaker) openErr() error {
+	return fmt.Errorf("%w: %s: %w", apperror.ErrUnavailable, b.name, ErrCircuitOpen)
+}
+
+// CircuitBreakerFn wraps a StageFn with a circuit breaker.
+func CircuitBreakerFn[T any](
+	name string,
+	fn ports.StageFn[T],
+	breaker *CircuitBreaker,
+) ports.StageFn[T] {
+	return func(ctx context.Context, m T) (T, error) {
+		res := m
+		err := breaker.Do(ctx, func(ctx context.Context) error {
+			var errFn error
+			res, errFn = fn(ctx, m)
+			return errFn
+		})
+		if err != nil {
+			return res, withStage(name, m, 1, err)
+		}
+		return res, nil
+	}
+}
+
+// CircuitBreakerStage wraps a channel stage with a circuit breaker. Each item
+// is run through the wrapped stage on its own; while the circuit is open
+// items fail without reaching the stage.
+type CircuitBreakerStage[T any] struct {
+	stage   ports.Stage[T]
+	breaker *CircuitBreaker
+}
+
+// NewCircuitBreakerStage creates a circuit breaking decorator around stage.
+func NewCircuitBreakerStage[T any](
+	stage ports.Stage[T],
+	breaker *CircuitBreaker,
+) *CircuitBreakerStage[T] {
+	return &CircuitBreakerStage[T]{stage: stage, breaker: breaker}
+}
+
+func (s *CircuitBreakerStage[T]) Name() string { return s.stage.Name() }
+
+func (s *CircuitBreakerStage[T]) Run(ctx context.Context, in <-chan T) (<-chan T, <-chan error) {
+	return runEach(ctx, s.Name(), in, s.process)
+}
+
+// process runs a single item through the breaker and returns its outputs,
+// or its errors if it failed.
+func (s *CircuitBreakerStage[T]) process(ctx context.Context, m T) ([]T, []error) {
+	var items []T
+	var errs []error
+	if err := s.breaker.Do(ctx, func(ctx context.Context) error {
+		items, errs = invokeStage(ctx, s.stage, m)
+		return errors.Join(errs...)
+	}); err != nil && len(errs) == 0 {
+		errs = []error{err}
+	}
+	if len(errs) == 0 {
+		return items, nil
+	}
+	for i, e := range errs {
+		errs[i] = withStage(s.Name(), m, 1, e)
+	}
+	return nil, errs
+}
+
+// BreakerProducer is a ports.MessageQueueProducer whose Produce calls go
+// through a circuit breaker.
+type BreakerProducer struct {
+	ports.MessageQueueProducer
+	breaker *CircuitBreaker
+}
+
+// NewBreakerProducer wraps producer with breaker.
+func NewBreakerProducer(
+	producer ports.MessageQueueProducer,
+	breaker *CircuitBreaker,
+) *BreakerProducer {
+	return &BreakerProducer{MessageQueueProducer: producer, breaker: breaker}
+}
+
+func (p *BreakerProducer) Produce(ctx context.Context, topic string, msg interface{}) error {
+	return p.breaker.Do(ctx, func(ctx context.Context) error {
+		return p.MessageQueueProducer.Produce(ctx, topic, msg)
+	})
+}
+
+var (
+	_ ports.Stage[any]           = (*CircuitBreakerStage[any])(nil)
+	_ ports.MessageQueueProducer = (*BreakerProducer)(nil)
+	_ ports.HealthChecker        = (*CircuitBreaker)(nil)
+)
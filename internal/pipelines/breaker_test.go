@@ -0,0 +1,110 @@
+package pipelines_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errDown = fmt.Errorf("%w: broker down", apperror.ErrUnavailable)
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := pipelines.NewManualClock(epoch)
+	var changes []string
+	b := pipelines.NewCircuitBreaker("kafka", pipelines.BreakerConfig{
+		FailureThreshold: 2,
+		Cooldown:         time.Second,
+	}).WithClock(clock).OnStateChange(func(name string, from, to pipelines.BreakerState) {
+		changes = append(changes, from.String()+"->"+to.String())
+	})
+
+	calls := 0
+	call := func(err error) error {
+		return b.Do(context.Background(), func(context.Context) error {
+			calls++
+			return err
+		})
+	}
+
+	// errors that are not failures of the dependency do not count
+	require.Error(t, call(apperror.ErrInvalidInput))
+	require.Error(t, call(errDown))
+	require.Error(t, call(apperror.ErrInvalidInput))
+	require.Error(t, call(errDown))
+	assert.Equal(t, pipelines.BreakerClosed, b.State())
+
+	require.Error(t, call(errDown))
+	assert.Equal(t, pipelines.BreakerOpen, b.State())
+
+	err := call(nil)
+	assert.True(t, errors.Is(err, apperror.ErrUnavailable))
+	assert.True(t, errors.Is(err, pipelines.ErrCircuitOpen))
+	assert.Error(t, b.Check(context.Background()))
+	assert.Equal(t, 5, calls)
+
+	clock.Advance(time.Second)
+	assert.Equal(t, pipelines.BreakerHalfOpen, b.State())
+	require.Error(t, call(errDown))
+	assert.Equal(t, pipelines.BreakerOpen, b.State())
+
+	clock.Advance(time.Second)
+	require.NoError(t, call(nil))
+	assert.Equal(t, pipelines.BreakerClosed, b.State())
+	assert.NoError(t, b.Check(context.Background()))
+
+	assert.Equal(t, []string{
+		"closed->open",
+		"open->half-open",
+		"half-open->open",
+		"open->half-open",
+		"half-open->closed",
+	}, changes)
+}
+
+func TestCircuitBreakerPanic(t *testing.T) {
+	clock := pipelines.NewManualClock(epoch)
+	b := pipelines.NewCircuitBreaker("kafka", pipelines.BreakerConfig{
+		FailureThreshold: 1,
+		Cooldown:         time.Second,
+	}).WithClock(clock)
+	boom := func(context.Context) error { panic("boom") }
+
+	assert.Panics(t, func() { _ = b.Do(context.Background(), boom) })
+	assert.Equal(t, pipelines.BreakerOpen, b.State())
+
+	// the panicking trial call must not keep the half-open slot taken
+	clock.Advance(time.Second)
+	assert.Panics(t, func() { _ = b.Do(context.Background(), boom) })
+	assert.Equal(t, pipelines.BreakerOpen, b.State())
+	clock.Advance(time.Second)
+	require.NoError(t, b.Do(context.Background(), func(context.Context) error { return nil }))
+	assert.Equal(t, pipelines.BreakerClosed, b.State())
+}
+
+func TestCircuitBreakerStage(t *testing.T) {
+	b := pipelines.NewCircuitBreaker("reject", pipelines.BreakerConfig{
+		FailureThreshold: 1,
+		Cooldown:         time.Hour,
+		IsFailure:        func(error) bool { return true },
+	})
+	stage := pipelines.NewCircuitBreakerStage[int](
+		&rejectStage{name: "reject", reject: rejectOdd}, b)
+
+	items, errs := collect(stage.Run(context.Background(), feed(2, 3, 4)))
+
+	assert.Equal(t, []int{2}, items)
+	require.Len(t, errs, 2)
+	assert.False(t, errors.Is(errs[0], pipelines.ErrCircuitOpen))
+	assert.True(t, errors.Is(errs[1], pipelines.ErrCircuitOpen))
+	se, ok := apperror.AsStageError[int](errs[1])
+	require.True(t, ok)
+	assert.Equal(t, 4, se.Item)
+}
@@ -0,0 +1,62 @@
+package pipelines
+
+import "context"
+
+// Buffers are the capacities a stage uses for its output and error channels.
+type Buffers struct {
+	Data int
+	Err  int
+}
+
+// DefaultBuffers are the buffers of stages that run outside of a runner
+// with a BufferPlan, and fill the sizes a plan leaves at zero. The service
+// plans the buffers of every pipeline, so they matter for stand-alone use.
+var DefaultBuffers = Buffers{Data: 64, Err: 64}
+
+// orElse fills the zero fields of b from def.
+func (b Buffers) orElse(def Buffers) Buffers {
+	if b.Data == 0 {
+		b.Data = def.Data
+	}
+	if b.Err == 0 {
+		b.Err = def.Err
+	}
+	return b
+}
+
+// BufferPlan holds the buffers of one pipeline: a default for all stages and
+// overrides by stage name. Zero fields of an override fall back to Default.
+type BufferPlan struct {
+	Default Buffers
+	Stages  map[string]Buffers
+}
+
+// For returns the buffers of the named stage.
+func (p BufferPlan) For(stage string) Buffers {
+	return p.Stages[stage].orElse(p.Default)
+}
+
+type buffersKey struct{}
+
+// ContextWithBuffers returns a copy of ctx carrying b.
+func ContextWithBuffers(ctx context.Context, b Buffers) context.Context {
+	return context.WithValue(ctx, buffersKey{}, b)
+}
+
+// BuffersFrom returns the buffers a runner planned for the stage it passed
+// ctx to. Outside of a runner with a BufferPlan, and for sizes the plan left
+// at zero, they are DefaultBuffers.
+func BuffersFrom(ctx context.Context) Buffers {
+	b, _ := ctx.Value(buffersKey{}).(Buffers)
+	return b.orElse(DefaultBuffers)
+}
+
+// stageContext passes the planned buffers of stage on to it. Without a plan
+// ctx is returned as is, so stages inside a router branch keep the buffers
+// of the enclosing pipeline.
+func stageContext(ctx context.Context, plan *BufferPlan, stage string) context.Context {
+	if plan == nil {
+		return ctx
+	}
+	return ContextWithBuffers(ctx, plan.For(stage))
+}
@@ -0,0 +1,136 @@
+package pipelines_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/internal/ports"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// probeStage passes items through and records the buffers it was given.
+type probeStage struct {
+	name string
+	mu   *sync.Mutex
+	seen map[string]pipelines.Buffers
+}
+
+func (s probeStage) Name() string { return s.name }
+
+func (s probeStage) Run(ctx context.Context, in <-chan int) (<-chan int, <-chan error) {
+	buf := pipelines.BuffersFrom(ctx)
+	s.mu.Lock()
+	s.seen[s.name] = buf
+	s.mu.Unlock()
+
+	out := make(chan int, buf.Data)
+	errCh := make(chan error, buf.Err)
+	go func() {
+		defer close(out)
+		defer close(errCh)
+		for m := range in {
+			out <- m
+		}
+	}()
+	return out, errCh
+}
+
+func TestRunnersWithBuffers(t *testing.T) {
+	plan := pipelines.BufferPlan{
+		Default: pipelines.Buffers{Data: 4, Err: 2},
+		Stages:  map[string]pipelines.Buffers{"b": {Data: 16}},
+	}
+	want := map[string]pipelines.Buffers{
+		"a": {Data: 4, Err: 2},
+		"b": {Data: 16, Err: 2},
+	}
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, a, b probeStage) ([]int, []error)
+	}{
+		{
+			name: "chain",
+			run: func(t *testing.T, a, b probeStage) ([]int, []error) {
+				r := pipelines.NewRunner[int](a, b).WithBuffers(plan)
+				return collect(r.Chain(context.Background(), feed(1, 2)))
+			},
+		},
+		{
+			name: "barrier",
+			run: func(t *testing.T, a, b probeStage) ([]int, []error) {
+				r := pipelines.NewRunnerBarrier[int](4, a, b).WithBuffers(plan)
+				return collect(r.Run(context.Background(), feed(1, 2)))
+			},
+		},
+		{
+			name: "dag",
+			run: func(t *testing.T, a, b probeStage) ([]int, []error) {
+				r, err := pipelines.NewRunnerDAG(
+					pipelines.Node[int](a),
+					pipelines.Node[int](b, "a"),
+				)
+				require.NoError(t, err)
+				return collect(r.WithBuffers(plan).Run(context.Background(), feed(1, 2)))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			seen := map[string]pipelines.Buffers{}
+			a := probeStage{name: "a", mu: &mu, seen: seen}
+			b := probeStage{name: "b", mu: &mu, seen: seen}
+
+			items, errs := tt.run(t, a, b)
+
+			assert.ElementsMatch(t, []int{1, 2}, items)
+			assert.Empty(t, errs)
+			assert.Equal(t, want, seen)
+		})
+	}
+}
+
+func TestBuffersFromUnset(t *testing.T) {
+	want := pipelines.DefaultBuffers
+	assert.Equal(t, want, pipelines.BuffersFrom(context.Background()))
+
+	// sizes a plan leaves at zero fall back as well
+	ctx := pipelines.ContextWithBuffers(context.Background(), pipelines.Buffers{Data: 3})
+	want.Data = 3
+	assert.Equal(t, want, pipelines.BuffersFrom(ctx))
+}
+
+func TestDecoratorsWithBuffers(t *testing.T) {
+	stage := pass("pass")
+	tests := []struct {
+		name  string
+		stage ports.Stage[int]
+	}{
+		{"Retry", pipelines.NewRetryStage[int](stage, pipelines.RetryPolicy{})},
+		{"Timeout", pipelines.NewTimeoutStage[int](stage, time.Second)},
+		{"CircuitBreaker", pipelines.NewCircuitBreakerStage[int](stage,
+			pipelines.NewCircuitBreaker("breaker", pipelines.BreakerConfig{}))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := pipelines.ContextWithBuffers(context.Background(),
+				pipelines.Buffers{Data: 3, Err: 2})
+
+			out, errCh := tt.stage.Run(ctx, feed(1))
+
+			assert.Equal(t, 3, cap(out))
+			assert.Equal(t, 2, cap(errCh))
+			items, errs := collect(out, errCh)
+			assert.Equal(t, []int{1}, items)
+			assert.Empty(t, errs)
+		})
+	}
+}
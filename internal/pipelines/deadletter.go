@@ -0,0 +1,57 @@
+package pipelines
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+)
+
+// Metadata keys passed to a ports.DeadLetterSink.
+const (
+	MetaStage     = "stage"
+	MetaErrorCode = "error-code"
+	MetaAttempt   = "attempt"
+)
+
+// deadLetter hands a failed item to sink. Only a StageError[T] carries the
+// item; any other error is left to the caller and the run listeners, which
+// count it, since there is nothing to dead-letter. It returns nil when there
+// is no item or the sink accepted it, and the sink's error otherwise.
+func deadLetter[T any](ctx context.Context, sink ports.DeadLetterSink[T], err error) error {
+	se, ok := apperror.AsStageError[T](err)
+	if !ok {
+		return nil
+	}
+	meta := map[string]string{
+		MetaStage:     se.Stage,
+		MetaErrorCode: apperror.JobCode(err),
+		MetaAttempt:   strconv.Itoa(se.Attempt),
+	}
+	if errDL := sink.DeadLetter(ctx, se.Item, err, meta); errDL != nil {
+		return fmt.Errorf("dead letter %s: %w", se.Stage, errDL)
+	}
+	return nil
+}
+
+// routeDeadLetters forwards a stage's errors and hands every failed item to
+// sink on the way. Sink failures are reported as additional errors.
+func routeDeadLetters[T any](
+	ctx context.Context,
+	sink ports.DeadLetterSink[T],
+	errCh <-chan error,
+) <-chan error {
+	out := make(chan error, cap(errCh))
+	go func() {
+		defer close(out)
+		for err := range errCh {
+			out <- err
+			if errDL := deadLetter(ctx, sink, err); errDL != nil {
+				out <- errDL
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,55 @@
+package pipelines
+
+import (
+	"context"
+	"maps"
+	"sync"
+
+	"go-pipeline/internal/ports"
+)
+
+// DeadLetter is a failed item recorded by MemoryDeadLetterSink.
+type DeadLetter[T any] struct {
+	Item     T
+	Err      error
+	Metadata map[string]string
+}
+
+// MemoryDeadLetterSink keeps dead letters in memory. It is meant for tests
+// and local runs where no broker is available.
+type MemoryDeadLetterSink[T any] struct {
+	mu      sync.Mutex
+	letters []DeadLetter[T]
+}
+
+// NewMemoryDeadLetterSink creates an empty in-memory sink.
+func NewMemoryDeadLetterSink[T any]() *MemoryDeadLetterSink[T] {
+	return &MemoryDeadLetterSink[T]{}
+}
+
+func (s *MemoryDeadLetterSink[T]) DeadLetter(
+	_ context.Context,
+	item T,
+	err error,
+	metadata map[string]string,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.letters = append(s.letters, DeadLetter[T]{
+		Item:     item,
+		Err:      err,
+		Metadata: maps.Clone(metadata),
+	})
+	return nil
+}
+
+// Letters returns a copy of the recorded dead letters.
+func (s *MemoryDeadLetterSink[T]) Letters() []DeadLetter[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]DeadLetter[T], len(s.letters))
+	copy(out, s.letters)
+	return out
+}
+
+var _ ports.DeadLetterSink[any] = (*MemoryDeadLetterSink[any])(nil)
@@ -0,0 +1,59 @@
+package pipelines
+
+import (
+	"context"
+	"time"
+
+	"go-pipeline/internal/ports"
+)
+
+// DeadLetterMessage is the payload published to a dead-letter topic.
+// The stage and error code are also sent as message headers; the producer
+// adds the trace-id header of the failed item's context.
+type DeadLetterMessage[T any] struct {
+	Item     T                 `json:"item"`
+	Error    string            `json:"error"`
+	FailedAt time.Time         `json:"failed_at"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// Headers implements ports.HeaderCarrier.
+func (m DeadLetterMessage[T]) Headers() map[string]string {
+	return map[string]string{
+		"dlq-stage":      m.Metadata[MetaStage],
+		"dlq-error-code": m.Metadata[MetaErrorCode],
+	}
+}
+
+// MQDeadLetterSink publishes failed items to a dead-letter topic.
+type MQDeadLetterSink[T any] struct {
+	producer ports.MessageQueueProducer
+	topic    string
+}
+
+// NewMQDeadLetterSink creates a sink that publishes to topic through producer.
+func NewMQDeadLetterSink[T any](
+	producer ports.MessageQueueProducer,
+	topic string,
+) *MQDeadLetterSink[T] {
+	return &MQDeadLetterSink[T]{producer: producer, topic: topic}
+}
+
+func (s *MQDeadLetterSink[T]) DeadLetter(
+	ctx context.Context,
+	item T,
+	err error,
+	metadata map[string]string,
+) error {
+	return s.producer.Produce(ctx, s.topic, DeadLetterMessage[T]{
+		Item:     item,
+		Error:    err.Error(),
+		FailedAt: time.Now().UTC(),
+		Metadata: metadata,
+	})
+}
+
+var (
+	_ ports.DeadLetterSink[any] = (*MQDeadLetterSink[any])(nil)
+	_ ports.HeaderCarrier       = DeadLetterMessage[any]{}
+)
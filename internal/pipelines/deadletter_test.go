@@ -0,0 +1,120 @@
+package pipelines_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rejectStage fails every item for which reject returns an error.
+type rejectStage struct {
+	name   string
+	reject func(int) error
+}
+
+func (s *rejectStage) Name() string { return s.name }
+
+func (s *rejectStage) Run(ctx context.Context, in <-chan int) (<-chan int, <-chan error) {
+	out := make(chan int, 8)
+	errCh := make(chan error, 8)
+	go func() {
+		defer close(out)
+		defer close(errCh)
+		for m := range in {
+			if err := s.reject(m); err != nil {
+				errCh <- apperror.NewStageError(s.name, m, 1, err)
+				continue
+			}
+			out <- m
+		}
+	}()
+	return out, errCh
+}
+
+func rejectOdd(m int) error {
+	if m%2 != 0 {
+		return fmt.Errorf("%w: odd %d", apperror.ErrInvalidInput, m)
+	}
+	return nil
+}
+
+func TestRunnerDeadLetter(t *testing.T) {
+	sink := pipelines.NewMemoryDeadLetterSink[int]()
+	runner := pipelines.NewRunner[int](&rejectStage{name: "even", reject: rejectOdd}).
+		WithDeadLetter(sink)
+
+	items, errs := collect(runner.Chain(context.Background(), feed(1, 2, 3, 4)))
+
+	assert.Equal(t, []int{2, 4}, items)
+	assert.Len(t, errs, 2)
+	letters := sink.Letters()
+	require.Len(t, letters, 2)
+	for i, want := range []int{1, 3} {
+		assert.Equal(t, want, letters[i].Item)
+		assert.ErrorIs(t, letters[i].Err, apperror.ErrInvalidInput)
+		assert.Equal(t, map[string]string{
+			pipelines.MetaStage:     "even",
+			pipelines.MetaErrorCode: "invalid_input",
+			pipelines.MetaAttempt:   "1",
+		}, letters[i].Metadata)
+	}
+}
+
+func TestRunnerShortCircuitDeadLetter(t *testing.T) {
+	sink := pipelines.NewMemoryDeadLetterSink[int]()
+	runner := pipelines.NewRunnerShortCircuit(
+		func(ctx context.Context, m int) (int, error) { return m, nil },
+		func(ctx context.Context, m int) (int, error) { return m, apperror.ErrUnavailable },
+	).WithDeadLetter(sink)
+
+	_, err := runner.Run(context.Background(), 5)
+
+	assert.ErrorIs(t, err, apperror.ErrUnavailable)
+	letters := sink.Letters()
+	require.Len(t, letters, 1)
+	assert.Equal(t, 5, letters[0].Item)
+	assert.Equal(t, "unavailable", letters[0].Metadata[pipelines.MetaErrorCode])
+}
+
+func TestRunnerDeadLetterUntypedError(t *testing.T) {
+	sink := pipelines.NewMemoryDeadLetterSink[int]()
+	runner := pipelines.NewRunner[int](untypedStage{}).WithDeadLetter(sink)
+
+	items, errs := collect(runner.Chain(context.Background(), feed(1, 2)))
+
+	assert.Equal(t, []int{2}, items)
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], apperror.ErrInvalidInput)
+	assert.Contains(t, errs[0].Error(), "stage untyped")
+	_, typed := apperror.AsStageError[int](errs[0])
+	assert.False(t, typed)
+	assert.Empty(t, sink.Letters())
+}
+
+// untypedStage fails odd items with errors that do not carry the item.
+type untypedStage struct{}
+
+func (untypedStage) Name() string { return "untyped" }
+
+func (untypedStage) Run(_ context.Context, in <-chan int) (<-chan int, <-chan error) {
+	out := make(chan int, 8)
+	errCh := make(chan error, 8)
+	go func() {
+		defer close(out)
+		defer close(errCh)
+		for m := range in {
+			if err := rejectOdd(m); err != nil {
+				errCh <- err
+				continue
+			}
+			out <- m
+		}
+	}()
+	return out, errCh
+}
@@ -0,0 +1,91 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+)
+
+// DedupMode decides what happens to an item whose key was already seen.
+type DedupMode int
+
+const (
+	// DedupSkip drops duplicates silently.
+	DedupSkip DedupMode = iota
+	// DedupReject fails duplicates with apperror.ErrDuplicateEntry.
+	DedupReject
+)
+
+// DedupStage calls fn only the first time an item's key is seen within the
+// TTL, which makes fn idempotent against redelivered or resubmitted items.
+// The key is recorded before fn runs, so concurrent duplicates wait for the
+// TTL rather than race fn, and is forgotten again when fn fails or panics,
+// so a failed item can be retried. Items the store cannot check fail with
+// the store's error.
+type DedupStage[T any] struct {
+	name  string
+	key   func(T) string
+	fn    ports.StageFn[T]
+	store ports.DedupStore
+	ttl   time.Duration
+	mode  DedupMode
+}
+
+// NewDedupStage creates a stage that calls fn for the items whose key(item)
+// is not in store yet and skips the others.
+func NewDedupStage[T any](
+	name string,
+	key func(T) string,
+	fn ports.StageFn[T],
+	store ports.DedupStore,
+	ttl time.Duration,
+) *DedupStage[T] {
+	return &DedupStage[T]{name: name, key: key, fn: fn, store: store, ttl: ttl}
+}
+
+// WithMode sets whether duplicates are skipped or rejected.
+func (s *DedupStage[T]) WithMode(mode DedupMode) *DedupStage[T] {
+	s.mode = mode
+	return s
+}
+
+func (s *DedupStage[T]) Name() string { return s.name }
+
+func (s *DedupStage[T]) Run(ctx context.Context, in <-chan T) (<-chan T, <-chan error) {
+	return RunItems[T](ctx, s, in)
+}
+
+// Process records the key of m and calls fn. A duplicate fails with
+// apperror.ErrDuplicateEntry or, when skipped, with ErrDropped.
+func (s *DedupStage[T]) Process(ctx context.Context, m T) (res T, err error) {
+	key := s.key(m)
+	first, err := s.store.MarkSeen(ctx, key, s.ttl)
+	switch {
+	case err != nil:
+		return m, err
+	case !first && s.mode == DedupReject:
+		return m, fmt.Errorf("%w: key %q already seen", apperror.ErrDuplicateEntry, key)
+	case !first:
+		return m, ErrDropped
+	}
+
+	done := false
+	defer func() {
+		if done && err == nil {
+			return
+		}
+		// forget even when ctx is done, or the key blocks retries for the TTL
+		if errForget := s.store.Forget(context.WithoutCancel(ctx), key); errForget != nil {
+			err = errors.Join(err, fmt.Errorf("key %q kept: %w", key, errForget))
+		}
+	}()
+	res, err = s.fn(ctx, m)
+	done = true
+	return res, err
+}
+
+var _ ports.ItemStage[any] = (*DedupStage[any])(nil)
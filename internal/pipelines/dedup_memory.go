@@ -0,0 +1,86 @@
+package pipelines
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"go-pipeline/internal/ports"
+)
+
+// MemoryDedupStore keeps the most recently seen keys in memory. Once it
+// holds capacity keys, marking a new one evicts the least recently seen, so
+// an evicted key counts as new again before its TTL is over. It is meant for
+// a single instance; replicas that share items need a shared store.
+type MemoryDedupStore struct {
+	mu       sync.Mutex
+	capacity int
+	clock    Clock
+	order    *list.List // front is the most recently seen key
+	entries  map[string]*list.Element
+}
+
+type dedupEntry struct {
+	key     string
+	expires time.Time
+}
+
+// NewMemoryDedupStore creates a store holding at most capacity keys; a
+// capacity below one is treated as one.
+func NewMemoryDedupStore(capacity int) *MemoryDedupStore {
+	return &MemoryDedupStore{
+		capacity: max(capacity, 1),
+		clock:    SystemClock,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// WithClock replaces the SystemClock.
+func (s *MemoryDedupStore) WithClock(c Clock) *MemoryDedupStore {
+	s.clock = c
+	return s
+}
+
+func (s *MemoryDedupStore) MarkSeen(
+	_ context.Context,
+	key string,
+	ttl time.Duration,
+) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if el, ok := s.entries[key]; ok {
+		entry := el.Value.(*dedupEntry)
+		if now.Before(entry.expires) {
+			s.order.MoveToFront(el)
+			return false, nil
+		}
+		entry.expires = now.Add(ttl)
+		s.order.MoveToFront(el)
+		return true, nil
+	}
+
+	s.entries[key] = s.order.PushFront(&dedupEntry{key: key, expires: now.Add(ttl)})
+	for s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*dedupEntry).key)
+	}
+	return true, nil
+}
+
+func (s *MemoryDedupStore) Forget(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		s.order.Remove(el)
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+var _ ports.DedupStore = (*MemoryDedupStore)(nil)
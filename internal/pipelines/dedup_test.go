@@ -0,0 +1,160 @@
+package pipelines_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type downStore struct{}
+
+func (downStore) MarkSeen(context.Context, string, time.Duration) (bool, error) {
+	return false, apperror.ErrUnavailable
+}
+
+func (downStore) Forget(context.Context, string) error { return apperror.ErrUnavailable }
+
+func keep(_ context.Context, s string) (string, error) { return s, nil }
+
+func TestDedupStage(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     pipelines.DedupMode
+		want     []string
+		wantDups int
+	}{
+		{"Skip", pipelines.DedupSkip, []string{"a", "b"}, 0},
+		{"Reject", pipelines.DedupReject, []string{"a", "b"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := pipelines.NewMemoryDedupStore(16)
+			stage := pipelines.NewDedupStage("dedup", identity, keep, store, time.Minute).
+				WithMode(tt.mode)
+
+			items, errs := collect(stage.Run(context.Background(), feed("a", "b", "a", "a")))
+
+			assert.Equal(t, tt.want, items)
+			require.Len(t, errs, tt.wantDups)
+			for _, err := range errs {
+				assert.True(t, errors.Is(err, apperror.ErrDuplicateEntry))
+				se, ok := apperror.AsStageError[string](err)
+				require.True(t, ok)
+				assert.Equal(t, "a", se.Item)
+			}
+		})
+	}
+}
+
+func TestDedupStageStoreDown(t *testing.T) {
+	stage := pipelines.NewDedupStage[string]("dedup", identity, keep, downStore{}, time.Minute)
+
+	items, errs := collect(stage.Run(context.Background(), feed("a")))
+
+	assert.Empty(t, items)
+	require.Len(t, errs, 1)
+	assert.True(t, errors.Is(errs[0], apperror.ErrUnavailable))
+}
+
+func TestDedupStageForgetsFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		fail func(s string) (string, error)
+	}{
+		{"Error", func(string) (string, error) { return "", apperror.ErrUnavailable }},
+		{"Panic", func(string) (string, error) { panic("boom") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			fn := func(_ context.Context, s string) (string, error) {
+				calls++
+				if calls == 1 {
+					return tt.fail(s)
+				}
+				return s, nil
+			}
+			store := pipelines.NewMemoryDedupStore(16)
+			stage := pipelines.NewDedupStage("dedup", identity, fn, store, time.Minute)
+
+			items, errs := collect(stage.Run(context.Background(), feed("a", "a", "a")))
+
+			// the failed "a" was forgotten, so the second one is retried and
+			// the third is a duplicate of it
+			assert.Equal(t, []string{"a"}, items)
+			assert.Len(t, errs, 1)
+			assert.Equal(t, 2, calls)
+		})
+	}
+}
+
+func TestDedupStageKeyPanics(t *testing.T) {
+	key := func(s string) string {
+		if s == "b" {
+			panic("no key")
+		}
+		return s
+	}
+	stage := pipelines.NewDedupStage("dedup", key, keep, pipelines.NewMemoryDedupStore(16),
+		time.Minute)
+
+	items, errs := collect(stage.Run(context.Background(), feed("a", "b", "c")))
+
+	assert.Equal(t, []string{"a", "c"}, items)
+	require.Len(t, errs, 1)
+	var pe *pipelines.PanicError
+	assert.ErrorAs(t, errs[0], &pe)
+}
+
+func TestMemoryDedupStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Expires", func(t *testing.T) {
+		clock := pipelines.NewManualClock(epoch)
+		store := pipelines.NewMemoryDedupStore(16).WithClock(clock)
+
+		first, err := store.MarkSeen(ctx, "a", time.Minute)
+		require.NoError(t, err)
+		assert.True(t, first)
+		first, _ = store.MarkSeen(ctx, "a", time.Minute)
+		assert.False(t, first)
+
+		clock.Advance(time.Minute)
+		first, _ = store.MarkSeen(ctx, "a", time.Minute)
+		assert.True(t, first)
+	})
+
+	t.Run("Forget", func(t *testing.T) {
+		store := pipelines.NewMemoryDedupStore(16)
+		_, err := store.MarkSeen(ctx, "a", time.Minute)
+		require.NoError(t, err)
+
+		require.NoError(t, store.Forget(ctx, "a"))
+		require.NoError(t, store.Forget(ctx, "b"))
+		first, _ := store.MarkSeen(ctx, "a", time.Minute)
+		assert.True(t, first)
+	})
+
+	t.Run("EvictsLeastRecent", func(t *testing.T) {
+		store := pipelines.NewMemoryDedupStore(2)
+		for _, key := range []string{"a", "b", "a", "c"} {
+			_, err := store.MarkSeen(ctx, key, time.Minute)
+			require.NoError(t, err)
+		}
+
+		// "a" was seen again after "b", so "b" made room for "c"
+		first, _ := store.MarkSeen(ctx, "a", time.Minute)
+		assert.False(t, first)
+		first, _ = store.MarkSeen(ctx, "b", time.Minute)
+		assert.True(t, first)
+	})
+}
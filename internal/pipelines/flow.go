@@ -0,0 +1,122 @@
+package pipelines
+
+import (
+	"context"
+
+	"go-pipeline/internal/ports"
+)
+
+// Flow is a chain of transformers whose types line up at compile time:
+// From starts a flow with a Transformer[A, B] and Then appends a
+// Transformer[B, C], giving a Flow[A, C]. Since every Stage[T] is a
+// Transformer[T, T], same-type stages can be mixed in freely.
+//
+// A Flow is itself a Transformer, and a Flow[T, T] is a ChainPipeline[T].
+type Flow[In, Out any] struct {
+	name string
+	run  func(ctx context.Context, in <-chan In) (<-chan Out, []<-chan error)
+}
+
+// From starts a flow with its first transformer.
+func From[In, Out any](t ports.Transformer[In, Out]) *Flow[In, Out] {
+	return &Flow[In, Out]{
+		name: t.Name(),
+		run: func(ctx context.Context, in <-chan In) (<-chan Out, []<-chan error) {
+			out, errCh := t.Run(ctx, in)
+			return out, []<-chan error{tagErrors[In](t.Name(), errCh)}
+		},
+	}
+}
+
+// Then appends a transformer that consumes the flow's output.
+func Then[In, Mid, Out any](f *Flow[In, Mid], t ports.Transformer[Mid, Out]) *Flow[In, Out] {
+	return &Flow[In, Out]{
+		name: f.name + " -> " + t.Name(),
+		run: func(ctx context.Context, in <-chan In) (<-chan Out, []<-chan error) {
+			mid, errs := f.run(ctx, in)
+			out, errCh := t.Run(ctx, mid)
+			return out, append(errs, tagErrors[Mid](t.Name(), errCh))
+		},
+	}
+}
+
+// ThenStage appends a same-type stage. It is Then for a ports.Stage, whose
+// type parameters cannot be inferred as a Transformer's.
+func ThenStage[In, T any](f *Flow[In, T], s ports.Stage[T]) *Flow[In, T] {
+	return Then[In, T, T](f, s)
+}
+
+// Name lists the names of the flow's transformers in order.
+func (f *Flow[In, Out]) Name() string { return f.name }
+
+// Run starts every transformer of the flow and merges their errors.
+func (f *Flow[In, Out]) Run(ctx context.Context, in <-chan In) (<-chan Out, <-chan error) {
+	out, errs := f.run(ctx, in)
+	return out, mergeErrors(errs...)
+}
+
+// Chain is Run under the name used by ports.ChainPipeline.
+func (f *Flow[In, Out]) Chain(ctx context.Context, in <-chan In) (<-chan Out, <-chan error) {
+	return f.Run(ctx, in)
+}
+
+// Convert turns a per-item conversion function into a Transformer. Failed
+// items are reported as *apperror.StageError[In] and are not emitted.
+func Convert[In, Out any](
+	name string,
+	fn func(ctx context.Context, m In) (Out, error),
+) ports.Transformer[In, Out] {
+	return &convertStage[In, Out]{name: name, fn: fn}
+}
+
+type convertStage[In, Out any] struct {
+	name string
+	fn   func(ctx context.Context, m In) (Out, error)
+}
+
+func (c *convertStage[In, Out]) Name() string { return c.name }
+
+func (c *convertStage[In, Out]) Run(
+	ctx context.Context,
+	in <-chan In,
+) (<-chan Out, <-chan error) {
+	out := make(chan Out)
+	errCh := make(chan error)
+
+	go func() {
+		defer close(out)
+		defer close(errCh)
+		defer recoverTo(ctx, c.name, errCh)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-in:
+				if !ok {
+					return
+				}
+				var zero Out
+				v, err := recovered(c.name, zero, func() (Out, error) { return c.fn(ctx, m) })
+				if err != nil {
+					select {
+					case errCh <- withStage(c.name, m, 1, err):
+					case <-ctx.Done():
+						return
+					}
+					continue
+				}
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, errCh
+}
+
+var (
+	_ ports.Transformer[any, int] = (*Flow[any, int])(nil)
+	_ ports.ChainPipeline[any]    = (*Flow[any, any])(nil)
+)
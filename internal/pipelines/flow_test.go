@@ -0,0 +1,53 @@
+package pipelines_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlow(t *testing.T) {
+	parse := pipelines.Convert("parse", func(ctx context.Context, s string) (int, error) {
+		return strconv.Atoi(s)
+	})
+	format := pipelines.Convert("format", func(ctx context.Context, m int) (string, error) {
+		return "#" + strconv.Itoa(m), nil
+	})
+
+	flow := pipelines.Then(
+		pipelines.ThenStage(pipelines.From(parse), &rejectStage{name: "even", reject: rejectOdd}),
+		format,
+	)
+
+	items, errs := collect(flow.Run(context.Background(), feed("2", "x", "3", "4")))
+
+	assert.Equal(t, "parse -> even -> format", flow.Name())
+	assert.Equal(t, []string{"#2", "#4"}, items)
+	require.Len(t, errs, 2)
+
+	var parseErr *apperror.StageError[string]
+	var evenErr *apperror.StageError[int]
+	for _, err := range errs {
+		if se, ok := apperror.AsStageError[string](err); ok {
+			parseErr = se
+		}
+		if se, ok := apperror.AsStageError[int](err); ok {
+			evenErr = se
+		}
+	}
+	require.NotNil(t, parseErr)
+	require.NotNil(t, evenErr)
+
+	var numErr *strconv.NumError
+	assert.Equal(t, "x", parseErr.Item)
+	assert.True(t, errors.As(parseErr, &numErr))
+	assert.Equal(t, "even", evenErr.Stage)
+	assert.Equal(t, 3, evenErr.Item)
+}
@@ -0,0 +1,80 @@
+package pipelines
+
+import (
+	"context"
+	"time"
+
+	"go-pipeline/internal/ports"
+)
+
+// StageCall describes one item processed by a stage.
+type StageCall struct {
+	Stage string
+	Item  any
+}
+
+// Interceptor wraps the processing of a single item by a stage, e.g. to log,
+// time or trace it. It calls next to process the item and returns next's
+// error, or an error of its own to fail the item instead. An interceptor
+// that does not call next drops the item.
+//
+// Runners intercept the Process calls of item stages (ports.ItemStage, e.g.
+// Map) and the steps of a short-circuit run. Other channel stages see their
+// whole input at once and run as they are.
+type Interceptor func(
+	ctx context.Context,
+	call StageCall,
+	next func(ctx context.Context) error,
+) error
+
+// Hooks builds an interceptor from a before and an after hook; either may
+// be nil. Before may return a derived context for the item, after sees how
+// long the item took and its error.
+func Hooks(
+	before func(ctx context.Context, call StageCall) context.Context,
+	after func(ctx context.Context, call StageCall, d time.Duration, err error),
+) Interceptor {
+	return func(ctx context.Context, call StageCall, next func(ctx context.Context) error) error {
+		if before != nil {
+			ctx = before(ctx, call)
+		}
+		start := time.Now()
+		err := next(ctx)
+		if after != nil {
+			after(ctx, call, time.Since(start), err)
+		}
+		return err
+	}
+}
+
+// intercept runs next through ics, the first one outermost.
+func intercept(
+	ctx context.Context,
+	ics []Interceptor,
+	call StageCall,
+	next func(ctx context.Context) error,
+) error {
+	if len(ics) == 0 {
+		return next(ctx)
+	}
+	return ics[0](ctx, call, func(ctx context.Context) error {
+		return intercept(ctx, ics[1:], call, next)
+	})
+}
+
+// InterceptFn wraps every call of fn with ics.
+func InterceptFn[T any](name string, fn ports.StageFn[T], ics ...Interceptor) ports.StageFn[T] {
+	if len(ics) == 0 {
+		return fn
+	}
+	return func(ctx context.Context, m T) (T, error) {
+		res := m
+		next := func(ctx context.Context) error {
+			var errFn error
+			res, errFn = fn(ctx, m)
+			return errFn
+		}
+		err := intercept(ctx, ics, StageCall{Stage: name, Item: m}, next)
+		return res, err
+	}
+}
@@ -0,0 +1,175 @@
+package pipelines_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is an interceptor that records every call it sees as
+// "<tag>:<stage>:<item>:<ok|err>".
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) intercept(tag string) pipelines.Interceptor {
+	after := func(_ context.Context, call pipelines.StageCall, _ time.Duration, err error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		outcome := "ok"
+		if err != nil {
+			outcome = "err"
+		}
+		r.calls = append(r.calls, fmt.Sprintf("%s:%s:%v:%s", tag, call.Stage, call.Item, outcome))
+	}
+	return pipelines.Hooks(nil, after)
+}
+
+func (r *recorder) sorted() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Sorted(slices.Values(r.calls))
+}
+
+func TestInterceptorsRunner(t *testing.T) {
+	rec := &recorder{}
+	even := pipelines.Map("even", func(_ context.Context, m int) (int, error) {
+		return m, rejectOdd(m)
+	})
+	runner := pipelines.NewRunner[int](even, pass("channel")).
+		WithInterceptors(rec.intercept("r"))
+
+	items, errs := collect(runner.Chain(context.Background(), feed(1, 2)))
+
+	assert.Equal(t, []int{2}, items)
+	assert.Len(t, errs, 1)
+	// the channel stage is not an item stage and runs unintercepted
+	assert.Equal(t, []string{"r:even:1:err", "r:even:2:ok"}, rec.sorted())
+}
+
+func TestInterceptorsSeePanics(t *testing.T) {
+	var got error
+	ic := pipelines.Hooks(nil, func(_ context.Context, _ pipelines.StageCall,
+		_ time.Duration, err error) {
+		got = err
+	})
+	boom := pipelines.Map("boom", func(context.Context, int) (int, error) { panic("boom") })
+
+	_, errs := collect(pipelines.NewRunner[int](boom).WithInterceptors(ic).
+		Chain(context.Background(), feed(1)))
+
+	require.Len(t, errs, 1)
+	requirePanic(t, got)
+}
+
+func TestInterceptorsOrder(t *testing.T) {
+	var order []string
+	mark := func(name string) pipelines.Interceptor {
+		return func(ctx context.Context, call pipelines.StageCall,
+			next func(ctx context.Context) error) error {
+			order = append(order, name+" before")
+			err := next(ctx)
+			order = append(order, name+" after")
+			return err
+		}
+	}
+	runner := pipelines.NewRunnerShortCircuitSteps(
+		pipelines.Step[int]{Name: "inc", Fn: func(ctx context.Context, m int) (int, error) {
+			order = append(order, "inc")
+			return m + 1, nil
+		}},
+	).WithInterceptors(mark("outer"), mark("inner"))
+
+	out, err := runner.Run(context.Background(), 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, out)
+	assert.Equal(t, []string{
+		"outer before", "inner before", "inc", "inner after", "outer after",
+	}, order)
+}
+
+func TestInterceptorsReplaceError(t *testing.T) {
+	errVeto := fmt.Errorf("%w: vetoed", apperror.ErrForbidden)
+	veto := func(ctx context.Context, call pipelines.StageCall,
+		next func(ctx context.Context) error) error {
+		if call.Item == 3 {
+			return errVeto
+		}
+		return next(ctx)
+	}
+	forgive := func(ctx context.Context, call pipelines.StageCall,
+		next func(ctx context.Context) error) error {
+		_ = next(ctx)
+		return nil
+	}
+
+	tests := []struct {
+		name      string
+		ic        pipelines.Interceptor
+		wantItems []int
+		wantErr   error
+	}{
+		{"interceptor error fails the item", veto, []int{2, 4}, errVeto},
+		{"swallowed error passes the item on", forgive, []int{2, 3, 4}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			even := pipelines.Map("even", func(_ context.Context, m int) (int, error) {
+				return m, rejectOdd(m)
+			})
+			runner := pipelines.NewRunner[int](even).WithInterceptors(tt.ic)
+
+			items, errs := collect(runner.Chain(context.Background(), feed(2, 3, 4)))
+
+			assert.Equal(t, tt.wantItems, items)
+			if tt.wantErr == nil {
+				assert.Empty(t, errs)
+				return
+			}
+			require.Len(t, errs, 1)
+			assert.True(t, errors.Is(errs[0], tt.wantErr))
+			se, ok := apperror.AsStageError[int](errs[0])
+			require.True(t, ok)
+			assert.Equal(t, 3, se.Item)
+		})
+	}
+}
+
+func TestInterceptorsKeepWorkers(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	slow := pipelines.Map("slow", func(_ context.Context, m int) (int, error) {
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		inFlight.Add(-1)
+		return m, nil
+	})
+	rec := &recorder{}
+	runner := pipelines.NewRunner(pipelines.NewWorkerStage[int](slow, 4)).
+		WithInterceptors(rec.intercept("w"))
+
+	items, errs := collect(runner.Chain(context.Background(), feed(1, 2, 3, 4)))
+
+	assert.Len(t, items, 4)
+	assert.Empty(t, errs)
+	assert.Greater(t, peak.Load(), int32(1))
+	assert.Len(t, rec.sorted(), 4)
+}
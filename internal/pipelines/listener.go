@@ -0,0 +1,235 @@
+package pipelines
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/generate"
+)
+
+// NopListener implements every ports.RunListener callback as a no-op.
+// Embed it to implement only the callbacks you need.
+type NopListener struct{}
+
+func (NopListener) OnRunStart(context.Context, ports.RunEvent)             {}
+func (NopListener) OnItemIn(context.Context, ports.RunEvent)               {}
+func (NopListener) OnItemOut(context.Context, ports.RunEvent)              {}
+func (NopListener) OnStageError(context.Context, ports.RunEvent)           {}
+func (NopListener) OnBarrierPhaseComplete(context.Context, ports.RunEvent) {}
+func (NopListener) OnRunEnd(context.Context, ports.RunEvent)               {}
+
+var _ ports.RunListener = NopListener{}
+
+// counts are the item and error counts of a run, a phase or a stage.
+type counts struct {
+	in, out, errs atomic.Int64
+}
+
+// runObserver reports one run to the listeners of its runner.
+type runObserver struct {
+	counts
+	listeners []ports.RunListener
+	id        string
+	start     time.Time
+}
+
+// startRun reports the start of a run. It returns nil when there are no
+// listeners; observing a nil run is a no-op.
+func startRun(ctx context.Context, listeners []ports.RunListener) *runObserver {
+	if len(listeners) == 0 {
+		return nil
+	}
+	o := &runObserver{listeners: listeners, id: generate.TraceID(), start: time.Now()}
+	ev := o.event("", &o.counts, nil)
+	for _, l := range o.listeners {
+		l.OnRunStart(ctx, ev)
+	}
+	return o
+}
+
+func (o *runObserver) event(stage string, c *counts, err error) ports.RunEvent {
+	return ports.RunEvent{
+		RunID:   o.id,
+		Stage:   stage,
+		Elapsed: time.Since(o.start),
+		In:      int(c.in.Load()),
+		Out:     int(c.out.Load()),
+		Errors:  int(c.errs.Load()),
+		Err:     err,
+	}
+}
+
+// count sets the totals of a run that does not stream.
+func (o *runObserver) count(in, out, errs int) {
+	if o == nil {
+		return
+	}
+	o.in.Store(int64(in))
+	o.out.Store(int64(out))
+	o.errs.Store(int64(errs))
+}
+
+// end reports the end of the run; err is what ended it early, if anything.
+func (o *runObserver) end(ctx context.Context, err error) {
+	if o == nil {
+		return
+	}
+	ev := o.event("", &o.counts, err)
+	for _, l := range o.listeners {
+		l.OnRunEnd(ctx, ev)
+	}
+}
+
+func (o *runObserver) itemIn(ctx context.Context, stage string, c *counts) {
+	if o == nil {
+		return
+	}
+	c.in.Add(1)
+	ev := o.event(stage, c, nil)
+	for _, l := range o.listeners {
+		l.OnItemIn(ctx, ev)
+	}
+}
+
+func (o *runObserver) itemOut(ctx context.Context, stage string, c *counts) {
+	if o == nil {
+		return
+	}
+	c.out.Add(1)
+	ev := o.event(stage, c, nil)
+	for _, l := range o.listeners {
+		l.OnItemOut(ctx, ev)
+	}
+}
+
+func (o *runObserver) stageError(ctx context.Context, stage string, c *counts, err error) {
+	if o == nil {
+		return
+	}
+	c.errs.Add(1)
+	ev := o.event(stage, c, err)
+	for _, l := range o.listeners {
+		l.OnStageError(ctx, ev)
+	}
+}
+
+func (o *runObserver) phaseComplete(
+	ctx context.Context,
+	stage string,
+	in, out, errs int,
+	took time.Duration,
+) {
+	if o == nil {
+		return
+	}
+	ev := ports.RunEvent{RunID: o.id, Stage: stage, Elapsed: took, In: in, Out: out, Errors: errs}
+	for _, l := range o.listeners {
+		l.OnBarrierPhaseComplete(ctx, ev)
+	}
+}
+
+// observe wraps stage so that its items and errors are reported to the
+// run, returning it as is when the run is not observed.
+func observe[T any](o *runObserver, stage ports.Stage[T]) ports.Stage[T] {
+	if o == nil {
+		return stage
+	}
+	return &observedStage[T]{stage: stage, run: o}
+}
+
+// observedStage reports every item that enters or leaves a stage and every
+// error it reports.
+type observedStage[T any] struct {
+	stage ports.Stage[T]
+	run   *runObserver
+	counts
+}
+
+func (s *observedStage[T]) Name() string { return s.stage.Name() }
+
+func (s *observedStage[T]) Run(ctx context.Context, in <-chan T) (<-chan T, <-chan error) {
+	name := s.Name()
+	src := tap(ctx, in, func(T) { s.run.itemIn(ctx, name, &s.counts) }, nil)
+	out, errCh := s.stage.Run(ctx, src)
+	out = tap(ctx, out, func(T) { s.run.itemOut(ctx, name, &s.counts) }, nil)
+	errCh = tapErrors(errCh, func(err error) { s.run.stageError(ctx, name, &s.counts, err) }, nil)
+	return out, errCh
+}
+
+// observeRun counts the input of a channel run.
+func observeRun[T any](ctx context.Context, o *runObserver, in <-chan T) <-chan T {
+	if o == nil {
+		return in
+	}
+	return tap(ctx, in, func(T) { o.in.Add(1) }, nil)
+}
+
+// finishRun counts the outputs and errors of a channel run and reports its
+// end once both channels are closed.
+func finishRun[T any](
+	ctx context.Context,
+	o *runObserver,
+	out <-chan T,
+	errCh <-chan error,
+) (<-chan T, <-chan error) {
+	if o == nil {
+		return out, errCh
+	}
+	var open atomic.Int32
+	open.Store(2)
+	closed := func() {
+		if open.Add(-1) == 0 {
+			o.end(ctx, ctx.Err())
+		}
+	}
+	out = tap(ctx, out, func(T) { o.out.Add(1) }, closed)
+	errCh = tapErrors(errCh, func(error) { o.errs.Add(1) }, closed)
+	return out, errCh
+}
+
+// tap forwards src, calling fn for every value and done, if set, once src
+// is drained or ctx is done. Like the stages it stops reading on ctx, so a
+// producer has to watch ctx as well.
+func tap[V any](ctx context.Context, src <-chan V, fn func(V), done func()) <-chan V {
+	out := make(chan V, cap(src))
+	go func() {
+		defer close(out)
+		if done != nil {
+			defer done()
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-src:
+				if !ok {
+					return
+				}
+				fn(v)
+				if !trySend(ctx, out, v) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// tapErrors is tap for error channels: like mergeErrors it forwards every
+// error, also after ctx is done, so no failure goes unreported.
+func tapErrors(src <-chan error, fn func(error), done func()) <-chan error {
+	out := make(chan error, cap(src))
+	go func() {
+		defer close(out)
+		for err := range src {
+			fn(err)
+			out <- err
+		}
+		if done != nil {
+			done()
+		}
+	}()
+	return out
+}
@@ -0,0 +1,152 @@
+package pipelines_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/internal/ports"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runLog records every event it is told about, keyed by callback.
+type runLog struct {
+	mu     sync.Mutex
+	events map[string][]ports.RunEvent
+}
+
+func newRunLog() *runLog { return &runLog{events: make(map[string][]ports.RunEvent)} }
+
+func (l *runLog) add(kind string, ev ports.RunEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events[kind] = append(l.events[kind], ev)
+}
+
+func (l *runLog) get(kind string) []ports.RunEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.events[kind]
+}
+
+// stages counts the events of one kind per stage.
+func (l *runLog) stages(kind string) map[string]int {
+	n := make(map[string]int)
+	for _, ev := range l.get(kind) {
+		n[ev.Stage]++
+	}
+	return n
+}
+
+func (l *runLog) OnRunStart(_ context.Context, ev ports.RunEvent) { l.add("start", ev) }
+func (l *runLog) OnItemIn(_ context.Context, ev ports.RunEvent)   { l.add("in", ev) }
+func (l *runLog) OnItemOut(_ context.Context, ev ports.RunEvent)  { l.add("out", ev) }
+func (l *runLog) OnStageError(_ context.Context, ev ports.RunEvent) {
+	l.add("error", ev)
+}
+func (l *runLog) OnBarrierPhaseComplete(_ context.Context, ev ports.RunEvent) {
+	l.add("phase", ev)
+}
+func (l *runLog) OnRunEnd(_ context.Context, ev ports.RunEvent) { l.add("end", ev) }
+
+func TestListenersRunner(t *testing.T) {
+	first, second := newRunLog(), newRunLog()
+	runner := pipelines.NewRunner[int](pass("pass"), &rejectStage{name: "even", reject: rejectOdd}).
+		WithListeners(first).
+		WithListeners(second)
+
+	items, errs := collect(runner.Chain(context.Background(), feed(1, 2, 3, 4)))
+
+	assert.Len(t, items, 2)
+	assert.Len(t, errs, 2)
+	for _, l := range []*runLog{first, second} {
+		require.Len(t, l.get("start"), 1)
+		require.Len(t, l.get("end"), 1)
+		end := l.get("end")[0]
+		assert.NotEmpty(t, end.RunID)
+		assert.Equal(t, []int{4, 2, 2}, []int{end.In, end.Out, end.Errors})
+		assert.Equal(t, map[string]int{"pass": 4, "even": 4}, l.stages("in"))
+		assert.Equal(t, map[string]int{"pass": 4, "even": 2}, l.stages("out"))
+		assert.Equal(t, map[string]int{"even": 2}, l.stages("error"))
+		for _, ev := range l.get("in") {
+			assert.Equal(t, end.RunID, ev.RunID)
+		}
+	}
+}
+
+func TestListenersCancel(t *testing.T) {
+	l := newRunLog()
+	// pass ranges over its input, it only stops once the taps close it
+	runner := pipelines.NewRunner[int](pass("pass")).WithListeners(l)
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int) // never closed
+
+	out, errCh := runner.Chain(ctx, in)
+	cancel()
+
+	// collect returns only once both channels are closed
+	items, errs := collect(out, errCh)
+	assert.Empty(t, items)
+	assert.Empty(t, errs)
+	assert.Len(t, l.get("end"), 1)
+}
+
+func TestListenersRunIDs(t *testing.T) {
+	log := newRunLog()
+	runner := pipelines.NewRunner[int](pass("pass")).WithListeners(log)
+
+	collect(runner.Chain(context.Background(), feed(1)))
+	collect(runner.Chain(context.Background(), feed(1)))
+
+	ends := log.get("end")
+	require.Len(t, ends, 2)
+	assert.NotEqual(t, ends[0].RunID, ends[1].RunID)
+}
+
+func TestListenersBarrier(t *testing.T) {
+	log := newRunLog()
+	runner := pipelines.NewRunnerBarrier[int](4, pass("pass"),
+		&rejectStage{name: "even", reject: rejectOdd}).
+		WithErrorPolicy(pipelines.FailFast()).
+		WithListeners(log)
+
+	items, errs := collect(runner.Run(context.Background(), feed(1, 2, 3, 4)))
+
+	assert.Empty(t, items)
+	assert.Len(t, errs, 3)
+	phases := log.get("phase")
+	require.Len(t, phases, 2)
+	assert.Equal(t, "pass", phases[0].Stage)
+	assert.Equal(t, []int{4, 4, 0}, []int{phases[0].In, phases[0].Out, phases[0].Errors})
+	assert.Equal(t, "even", phases[1].Stage)
+	assert.Equal(t, []int{4, 2, 2}, []int{phases[1].In, phases[1].Out, phases[1].Errors})
+
+	end := log.get("end")[0]
+	assert.ErrorIs(t, end.Err, pipelines.ErrPhaseAborted)
+	assert.Equal(t, []int{4, 0, 3}, []int{end.In, end.Out, end.Errors})
+}
+
+func TestListenersShortCircuit(t *testing.T) {
+	log := newRunLog()
+	runner := pipelines.NewRunnerShortCircuitSteps(
+		pipelines.Step[int]{Name: "inc", Fn: func(ctx context.Context, m int) (int, error) {
+			return m + 1, nil
+		}},
+		pipelines.Step[int]{Name: "even", Fn: func(ctx context.Context, m int) (int, error) {
+			return m, rejectOdd(m)
+		}},
+	).WithListeners(log)
+
+	_, err := runner.Run(context.Background(), 2)
+
+	require.Error(t, err)
+	assert.Equal(t, map[string]int{"inc": 1, "even": 1}, log.stages("in"))
+	assert.Equal(t, map[string]int{"inc": 1}, log.stages("out"))
+	assert.Equal(t, map[string]int{"even": 1}, log.stages("error"))
+	end := log.get("end")[0]
+	assert.ErrorIs(t, end.Err, err)
+	assert.Equal(t, []int{1, 0, 1}, []int{end.In, end.Out, end.Errors})
+}
@@ -0,0 +1,147 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+)
+
+// PanicError reports a stage that panicked on an item. It is classified as
+// apperror.ErrInternal and carries the panic value and the stack.
+type PanicError struct {
+	Stage string
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in stage %s: %v", e.Stage, e.Value)
+}
+
+func (e *PanicError) Unwrap() error { return apperror.ErrInternal }
+
+// recovered calls fn and turns a panic into a *PanicError.
+func recovered[T any](stage string, m T, fn func() (T, error)) (res T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			res = m
+			err = &PanicError{Stage: stage, Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
+
+// recoverTo is deferred by the goroutine of a built-in channel stage, after
+// the deferred closes of its channels so that it runs first. It reports a
+// panic as a *PanicError on errCh and lets the stage end as if its input was
+// drained. Stages written outside this package have to recover in their own
+// goroutines, or be item stages, which RunItems recovers item by item.
+func recoverTo(ctx context.Context, stage string, errCh chan<- error) {
+	if r := recover(); r != nil {
+		trySend[error](ctx, errCh, &PanicError{Stage: stage, Value: r, Stack: debug.Stack()})
+	}
+}
+
+// RunItems runs an item stage: it calls Process for every item of in and
+// emits the results, closing both channels once in is drained or ctx is
+// done. An item failing with ErrDropped is dropped without an error. A
+// panic fails the item with a *PanicError and the stage goes on with the
+// next item, unless it is a ports.PanicStopper that asks to stop.
+func RunItems[T any](
+	ctx context.Context,
+	stage ports.ItemStage[T],
+	in <-chan T,
+) (<-chan T, <-chan error) {
+	buf := BuffersFrom(ctx)
+	out := make(chan T, buf.Data)
+	errCh := make(chan error, buf.Err)
+	stop := false
+	if ps, ok := stage.(ports.PanicStopper); ok {
+		stop = ps.StopOnPanic()
+	}
+
+	go func() {
+		defer close(out)
+		defer close(errCh)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-in:
+				if !ok {
+					return
+				}
+				res, err := recovered(stage.Name(), m, func() (T, error) {
+					return stage.Process(ctx, m)
+				})
+				if err == nil {
+					if !trySend(ctx, out, res) {
+						return
+					}
+					continue
+				}
+				if errors.Is(err, ErrDropped) {
+					continue
+				}
+				if !trySend(ctx, errCh, withStage(stage.Name(), m, 1, err)) {
+					return
+				}
+				var pe *PanicError
+				if errors.As(err, &pe) && stop {
+					return
+				}
+			}
+		}
+	}()
+	return out, errCh
+}
+
+// isolate makes the runner drive item stages through RunItems, so their
+// panics are recovered whatever their own Run does, and every Process call
+// passes through ics. Worker stages keep their workers; other stages are
+// returned as is.
+func isolate[T any](stage ports.Stage[T], ics ...Interceptor) ports.Stage[T] {
+	switch s := stage.(type) {
+	case *WorkerStage[T]:
+		return &WorkerStage[T]{stage: isolate(s.stage, ics...), workers: s.workers}
+	case *isolatedStage[T]:
+		if len(ics) == 0 {
+			return s
+		}
+		return &isolatedStage[T]{stage: s, ics: ics}
+	case ports.ItemStage[T]:
+		return &isolatedStage[T]{stage: s, ics: ics}
+	default:
+		return stage
+	}
+}
+
+type isolatedStage[T any] struct {
+	stage ports.ItemStage[T]
+	ics   []Interceptor
+}
+
+func (s *isolatedStage[T]) Name() string { return s.stage.Name() }
+
+func (s *isolatedStage[T]) Run(ctx context.Context, in <-chan T) (<-chan T, <-chan error) {
+	return RunItems[T](ctx, s, in)
+}
+
+// Process recovers a panic of the stage inside the interceptors, so they
+// see it as the item's *PanicError.
+func (s *isolatedStage[T]) Process(ctx context.Context, m T) (T, error) {
+	return InterceptFn(s.Name(), func(ctx context.Context, m T) (T, error) {
+		return recovered(s.Name(), m, func() (T, error) { return s.stage.Process(ctx, m) })
+	}, s.ics...)(ctx, m)
+}
+
+func (s *isolatedStage[T]) StopOnPanic() bool {
+	ps, ok := s.stage.(ports.PanicStopper)
+	return ok && ps.StopOnPanic()
+}
+
+var _ ports.ItemStage[any] = (*isolatedStage[any])(nil)
@@ -0,0 +1,134 @@
+package pipelines_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// panicStage passes items on and panics on item at. Its own Run does not
+// recover, so only the runner can keep the process alive.
+type panicStage struct {
+	at   int
+	stop bool
+}
+
+func (s *panicStage) Name() string { return "panicky" }
+
+func (s *panicStage) Run(ctx context.Context, in <-chan int) (<-chan int, <-chan error) {
+	out := make(chan int)
+	errCh := make(chan error)
+	go func() {
+		defer close(out)
+		defer close(errCh)
+		for m := range in {
+			v, err := s.Process(ctx, m)
+			if err != nil {
+				errCh <- err
+				continue
+			}
+			out <- v
+		}
+	}()
+	return out, errCh
+}
+
+func (s *panicStage) Process(ctx context.Context, m int) (int, error) {
+	if m == s.at {
+		panic("boom")
+	}
+	return m, nil
+}
+
+func (s *panicStage) StopOnPanic() bool { return s.stop }
+
+func requirePanic(t *testing.T, err error) {
+	t.Helper()
+	assert.True(t, errors.Is(err, apperror.ErrInternal), err)
+	var pe *pipelines.PanicError
+	require.True(t, errors.As(err, &pe))
+	assert.Equal(t, "boom", pe.Value)
+	assert.NotEmpty(t, pe.Stack)
+}
+
+func TestPanicIsolationRunners(t *testing.T) {
+	tests := []struct {
+		name      string
+		stop      bool
+		wantItems []int
+	}{
+		{"continues with the next item", false, []int{1, 3, 4}},
+		{"stops when the stage opts out", true, []int{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stage := &panicStage{at: 2, stop: tt.stop}
+			runners := map[string]func() ([]int, []error){
+				"chain": func() ([]int, []error) {
+					return collect(pipelines.NewRunner[int](stage).
+						Chain(context.Background(), feed(1, 2, 3, 4)))
+				},
+				"barrier": func() ([]int, []error) {
+					return collect(pipelines.NewRunnerBarrier[int](4, stage).
+						Run(context.Background(), feed(1, 2, 3, 4)))
+				},
+			}
+			for name, run := range runners {
+				items, errs := run()
+				assert.Equal(t, tt.wantItems, items, name)
+				require.Len(t, errs, 1, name)
+				requirePanic(t, errs[0])
+				se, ok := apperror.AsStageError[int](errs[0])
+				require.True(t, ok)
+				assert.Equal(t, 2, se.Item)
+			}
+		})
+	}
+}
+
+func TestPanicIsolationFn(t *testing.T) {
+	boom := func(ctx context.Context, m int) (int, error) { panic("boom") }
+
+	_, err := pipelines.NewRunnerShortCircuit[int](boom).Run(context.Background(), 1)
+	requirePanic(t, err)
+
+	_, err = pipelines.TimeoutFn("boom", boom, time.Second)(context.Background(), 1)
+	requirePanic(t, err)
+}
+
+func TestPanicIsolationChannelStages(t *testing.T) {
+	boom := func(int) bool { panic("boom") }
+	tests := []struct {
+		name  string
+		stage ports.Stage[int]
+	}{
+		{"Router", pipelines.NewRouterStage("router", pipelines.Route[int]{
+			Match:    boom,
+			Pipeline: pipelines.Branch[int](),
+		})},
+		{"Ordered", pipelines.NewOrderedStage("ordered",
+			func(context.Context, int) (int, error) { panic("boom") }, 2, 2)},
+		{"Retry", pipelines.NewRetryStage[int](&panicStage{at: 1}, pipelines.RetryPolicy{})},
+		{"Timeout", pipelines.NewTimeoutStage[int](&panicStage{at: 1}, time.Second)},
+		{"Filter", pipelines.Filter("filter", boom)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// collect returns only once the stage has closed both channels
+			items, errs := collect(tt.stage.Run(context.Background(), feed(1)))
+
+			assert.Empty(t, items)
+			require.Len(t, errs, 1)
+			requirePanic(t, errs[0])
+		})
+	}
+}
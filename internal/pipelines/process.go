@@ -0,0 +1,69 @@
+package pipelines
+
+import (
+	"context"
+
+	"go-pipeline/internal/ports"
+)
+
+// Process runs a batch of items through the pipeline and waits for it.
+func (r *Runner[T]) Process(ctx context.Context, items []T) ([]T, []error) {
+	return drain(r.Chain(ctx, emitAll(items)))
+}
+
+// Process runs a batch of items through the pipeline and waits for it.
+func (r *RunnerBarrier[T]) Process(ctx context.Context, items []T) ([]T, []error) {
+	return drain(r.Run(ctx, emitAll(items)))
+}
+
+// Process runs a batch of items through the pipeline and waits for it.
+func (r *RunnerDAG[T]) Process(ctx context.Context, items []T) ([]T, []error) {
+	return drain(r.Run(ctx, emitAll(items)))
+}
+
+// Process runs every item through the steps, one item after the other.
+// Dropped items are left out of the outputs.
+func (r *RunnerShortCircuit[T]) Process(ctx context.Context, items []T) ([]T, []error) {
+	var outs []T
+	var errs []error
+	for _, m := range items {
+		out, kept, err := r.run(ctx, m, nil, false)
+		switch {
+		case err != nil:
+			errs = append(errs, err)
+		case kept:
+			outs = append(outs, out)
+		}
+	}
+	return outs, errs
+}
+
+// drain collects everything a run emits until both channels are closed.
+func drain[T any](out <-chan T, errCh <-chan error) ([]T, []error) {
+	var items []T
+	var errs []error
+	for out != nil || errCh != nil {
+		select {
+		case m, ok := <-out:
+			if !ok {
+				out = nil
+				continue
+			}
+			items = append(items, m)
+		case e, ok := <-errCh:
+			if !ok {
+				errCh = nil
+				continue
+			}
+			errs = append(errs, e)
+		}
+	}
+	return items, errs
+}
+
+var (
+	_ ports.BatchPipeLine[any] = (*Runner[any])(nil)
+	_ ports.BatchPipeLine[any] = (*RunnerBarrier[any])(nil)
+	_ ports.BatchPipeLine[any] = (*RunnerDAG[any])(nil)
+	_ ports.BatchPipeLine[any] = (*RunnerShortCircuit[any])(nil)
+)
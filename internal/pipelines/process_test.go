@@ -0,0 +1,48 @@
+package pipelines_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess(t *testing.T) {
+	even := func() *rejectStage { return &rejectStage{name: "even", reject: rejectOdd} }
+	evenFn := func(ctx context.Context, m int) (int, error) {
+		return m, rejectOdd(m)
+	}
+	dag, err := pipelines.NewRunnerDAG(pipelines.Node[int](even()))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		pipeline ports.BatchPipeLine[int]
+	}{
+		{"chain", pipelines.NewRunner[int](even())},
+		{"barrier", pipelines.NewRunnerBarrier[int](4, even())},
+		{"dag", dag},
+		{"short", pipelines.NewRunnerShortCircuitSteps(
+			pipelines.Step[int]{Name: "even", Fn: evenFn},
+		)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, errs := tt.pipeline.Process(context.Background(), []int{1, 2, 3, 4})
+
+			slices.Sort(items)
+			assert.Equal(t, []int{2, 4}, items)
+			require.Len(t, errs, 2)
+			for _, e := range errs {
+				assert.True(t, errors.Is(e, apperror.ErrInvalidInput))
+			}
+		})
+	}
+}
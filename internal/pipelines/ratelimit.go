@@ -0,0 +1,254 @@
+package pipelines
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+)
+
+// RateMode decides what happens to an item once a rate limit is hit.
+type RateMode int
+
+const (
+	// RateBlock waits for a token, which pushes back on the upstream stages.
+	RateBlock RateMode = iota
+	// RateReject fails the item with apperror.ErrTooMany.
+	RateReject
+)
+
+// sweepKeys is the number of per-key buckets after which idle buckets are
+// dropped, so a stream of distinct keys does not grow the limiter forever.
+const sweepKeys = 1024
+
+// RateLimit is a token bucket refilled with Rate tokens per second that holds
+// at most Burst tokens. A zero Rate means no limit.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+type tokenBucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(limit RateLimit, now time.Time) *tokenBucket {
+	return &tokenBucket{limit: limit, tokens: float64(max(limit.Burst, 1)), last: now}
+}
+
+// refill adds the tokens earned since the last call.
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.tokens+elapsed.Seconds()*b.limit.Rate, float64(max(b.limit.Burst, 1)))
+		b.last = now
+	}
+}
+
+// full reports whether the bucket has refilled completely, i.e. it is idle.
+func (b *tokenBucket) full(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= float64(max(b.limit.Burst, 1))
+}
+
+// wait returns how long it takes until a token is available.
+func (b *tokenBucket) wait() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return max(time.Duration((1-b.tokens)/b.limit.Rate*float64(time.Second)), time.Nanosecond)
+}
+
+// RateLimiter hands out tokens from a global bucket and, optionally, from one
+// bucket per item key. An item needs a token from both. A single limiter can
+// be shared by several stages to protect the same dependency.
+type RateLimiter[T any] struct {
+	mu           sync.Mutex
+	global       RateLimit
+	perKey       RateLimit
+	key          func(T) string
+	mode         RateMode
+	clock        Clock
+	globalBucket *tokenBucket
+	buckets      map[string]*tokenBucket
+}
+
+// NewRateLimiter creates a blocking limiter with a global limit.
+func NewRateLimiter[T any](global RateLimit) *RateLimiter[T] {
+	return &RateLimiter[T]{
+		global:  global,
+		clock:   SystemClock,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// WithPerKey adds a limit per key(item) on top of the global limit.
+func (l *RateLimiter[T]) WithPerKey(key func(T) string, limit RateLimit) *RateLimiter[T] {
+	l.key = key
+	l.perKey = limit
+	return l
+}
+
+// WithMode sets whether limited items wait or fail.
+func (l *RateLimiter[T]) WithMode(mode RateMode) *RateLimiter[T] {
+	l.mode = mode
+	return l
+}
+
+// WithClock replaces the SystemClock.
+func (l *RateLimiter[T]) WithClock(c Clock) *RateLimiter[T] {
+	l.clock = c
+	return l
+}
+
+// Acquire takes a token for m. In RateBlock mode it waits until the tokens
+// are available or ctx is done; in RateReject mode it fails immediately with
+// apperror.ErrTooMany.
+func (l *RateLimiter[T]) Acquire(ctx context.Context, m T) error {
+	for {
+		wait, err := l.take(m)
+		if err != nil || wait == 0 {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-l.clock.After(wait):
+		}
+	}
+}
+
+// take consumes the tokens for m if both buckets have one. Otherwise it
+// returns how long to wait, or ErrTooMany in RateReject mode.
+func (l *RateLimiter[T]) take(m T) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	need := make([]*tokenBucket, 0, 2)
+	if l.global.Rate > 0 {
+		if l.globalBucket == nil {
+			l.globalBucket = newTokenBucket(l.global, now)
+		}
+		need = append(need, l.globalBucket)
+	}
+	if l.key != nil && l.perKey.Rate > 0 {
+		need = append(need, l.keyBucket(l.key(m), now))
+	}
+
+	var wait time.Duration
+	for _, b := range need {
+		b.refill(now)
+		wait = max(wait, b.wait())
+	}
+	if wait > 0 {
+		if l.mode == RateReject {
+			return 0, fmt.Errorf("%w: rate limit exceeded", apperror.ErrTooMany)
+		}
+		return wait, nil
+	}
+	for _, b := range need {
+		b.tokens--
+	}
+	return 0, nil
+}
+
+// keyBucket returns the bucket of key, creating it on first use. It must be
+// called with mu held.
+func (l *RateLimiter[T]) keyBucket(key string, now time.Time) *tokenBucket {
+	if b, ok := l.buckets[key]; ok {
+		return b
+	}
+	if len(l.buckets) >= sweepKeys {
+		for k, b := range l.buckets {
+			if b.full(now) {
+				delete(l.buckets, k)
+			}
+		}
+	}
+	b := newTokenBucket(l.perKey, now)
+	l.buckets[key] = b
+	return b
+}
+
+// RateLimitStage passes items through at the pace allowed by its limiter.
+// Rejected items are reported as *apperror.StageError wrapping ErrTooMany.
+type RateLimitStage[T any] struct {
+	name    string
+	limiter *RateLimiter[T]
+}
+
+// NewRateLimitStage creates a throttling stage, usually placed right before
+// the stage it protects.
+func NewRateLimitStage[T any](name string, limiter *RateLimiter[T]) *RateLimitStage[T] {
+	return &RateLimitStage[T]{name: name, limiter: limiter}
+}
+
+func (s *RateLimitStage[T]) Name() string { return s.name }
+
+func (s *RateLimitStage[T]) Run(ctx context.Context, in <-chan T) (<-chan T, <-chan error) {
+	out := make(chan T)
+	errCh := make(chan error)
+
+	go func() {
+		defer close(out)
+		defer close(errCh)
+		defer recoverTo(ctx, s.name, errCh)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-in:
+				if !ok {
+					return
+				}
+				err := s.limiter.Acquire(ctx, m)
+				if ctx.Err() != nil {
+					return
+				}
+				if err != nil {
+					if !trySend(ctx, errCh, withStage(s.name, m, 1, err)) {
+						return
+					}
+					continue
+				}
+				if !trySend(ctx, out, m) {
+					return
+				}
+			}
+		}
+	}()
+	return out, errCh
+}
+
+// RateLimitFn wraps a StageFn so that every call first takes a token from
+// limiter.
+func RateLimitFn[T any](
+	name string,
+	fn ports.StageFn[T],
+	limiter *RateLimiter[T],
+) ports.StageFn[T] {
+	return func(ctx context.Context, m T) (T, error) {
+		if err := limiter.Acquire(ctx, m); err != nil {
+			return m, withStage(name, m, 1, err)
+		}
+		return fn(ctx, m)
+	}
+}
+
+// trySend sends v unless ctx is done first. It returns false when ctx is
+// done.
+func trySend[V any](ctx context.Context, ch chan<- V, v V) bool {
+	select {
+	case ch <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+var _ ports.Stage[any] = (*RateLimitStage[any])(nil)
@@ -0,0 +1,88 @@
+package pipelines_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func identity(s string) string { return s }
+
+func TestRateLimitStageReject(t *testing.T) {
+	tests := []struct {
+		name    string
+		limiter *pipelines.RateLimiter[string]
+		items   []string
+		want    []string
+	}{
+		{
+			name:    "global burst",
+			limiter: pipelines.NewRateLimiter[string](pipelines.RateLimit{Rate: 1, Burst: 2}),
+			items:   []string{"a", "b", "c"},
+			want:    []string{"a", "b"},
+		},
+		{
+			name: "per key",
+			limiter: pipelines.NewRateLimiter[string](pipelines.RateLimit{}).
+				WithPerKey(identity, pipelines.RateLimit{Rate: 1, Burst: 1}),
+			items: []string{"a", "a", "b"},
+			want:  []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := tt.limiter.WithMode(pipelines.RateReject).
+				WithClock(pipelines.NewManualClock(epoch))
+			stage := pipelines.NewRateLimitStage("limit", limiter)
+
+			items, errs := collect(stage.Run(context.Background(), feed(tt.items...)))
+
+			assert.Equal(t, tt.want, items)
+			require.Len(t, errs, 1)
+			assert.True(t, errors.Is(errs[0], apperror.ErrTooMany))
+			se, ok := apperror.AsStageError[string](errs[0])
+			require.True(t, ok)
+			assert.Equal(t, "limit", se.Stage)
+		})
+	}
+}
+
+func TestRateLimitStageBlock(t *testing.T) {
+	clock := pipelines.NewManualClock(epoch)
+	limiter := pipelines.NewRateLimiter[int](pipelines.RateLimit{Rate: 2, Burst: 1}).
+		WithClock(clock)
+	out, errCh := pipelines.NewRateLimitStage("limit", limiter).
+		Run(context.Background(), feed(1, 2))
+
+	assert.Equal(t, 1, <-out)
+	clock.BlockUntil(1)
+	clock.Advance(500 * time.Millisecond)
+
+	items, errs := collect(out, errCh)
+	assert.Equal(t, []int{2}, items)
+	assert.Empty(t, errs)
+}
+
+func TestRateLimitFn(t *testing.T) {
+	limiter := pipelines.NewRateLimiter[int](pipelines.RateLimit{Rate: 1, Burst: 1}).
+		WithMode(pipelines.RateReject).
+		WithClock(pipelines.NewManualClock(epoch))
+	fn := pipelines.RateLimitFn("limit", func(ctx context.Context, m int) (int, error) {
+		return m * 10, nil
+	}, limiter)
+
+	got, err := fn(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 10, got)
+
+	_, err = fn(context.Background(), 2)
+	assert.True(t, errors.Is(err, apperror.ErrTooMany))
+}
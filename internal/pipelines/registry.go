@@ -0,0 +1,238 @@
+package pipelines
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+)
+
+// Options holds the parsed options of a stage. Every declared option has a
+// value, its default when it was not set. The typed getters return the zero
+// value for undeclared options.
+type Options struct {
+	values map[string]string
+}
+
+// ParseOptions checks raw against specs and fills in the defaults. Unknown
+// options, missing required ones and values that do not parse as their kind
+// are all reported, each wrapping apperror.ErrInvalidInput.
+func ParseOptions(specs []ports.OptionSpec, raw map[string]string) (Options, error) {
+	var errs []error
+	for name := range raw {
+		if !slices.ContainsFunc(specs, func(s ports.OptionSpec) bool { return s.Name == name }) {
+			errs = append(errs, fmt.Errorf("%w: unknown option %q", apperror.ErrInvalidInput, name))
+		}
+	}
+
+	values := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		v, ok := raw[spec.Name]
+		if !ok {
+			if spec.Required {
+				errs = append(errs, fmt.Errorf("%w: option %q is required",
+					apperror.ErrInvalidInput, spec.Name))
+				continue
+			}
+			v = spec.Default
+		}
+		if err := checkKind(spec.Kind, v); err != nil {
+			errs = append(errs, fmt.Errorf("%w: option %q: %v",
+				apperror.ErrInvalidInput, spec.Name, err))
+			continue
+		}
+		values[spec.Name] = v
+	}
+	if len(errs) > 0 {
+		slices.SortFunc(errs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+		return Options{}, errors.Join(errs...)
+	}
+	return Options{values: values}, nil
+}
+
+func checkKind(kind ports.OptionKind, v string) error {
+	if v == "" {
+		return nil
+	}
+	var err error
+	switch kind {
+	case ports.OptionInt:
+		_, err = strconv.Atoi(v)
+	case ports.OptionBool:
+		_, err = strconv.ParseBool(v)
+	case ports.OptionDuration:
+		_, err = time.ParseDuration(v)
+	case ports.OptionString:
+	default:
+		err = fmt.Errorf("unknown kind %q", kind)
+	}
+	return err
+}
+
+func (o Options) String(name string) string { return o.values[name] }
+
+func (o Options) Int(name string) int {
+	v, _ := strconv.Atoi(o.values[name])
+	return v
+}
+
+func (o Options) Bool(name string) bool {
+	v, _ := strconv.ParseBool(o.values[name])
+	return v
+}
+
+func (o Options) Duration(name string) time.Duration {
+	v, _ := time.ParseDuration(o.values[name])
+	return v
+}
+
+// StageFactory builds a registered stage from its dependencies D and its
+// options. Stage builds the channel form, Fn the function form; a factory
+// provides at least one of them, and the registry adapts it to the other
+// with FromFn or ToFn.
+type StageFactory[T, D any] struct {
+	Name        string
+	Description string
+	Options     []ports.OptionSpec
+	Stage       func(deps D, opts Options) (ports.Stage[T], error)
+	Fn          func(deps D, opts Options) (ports.StageFn[T], error)
+}
+
+// Spec describes the factory for tooling.
+func (f StageFactory[T, D]) Spec() ports.StageSpec {
+	return ports.StageSpec{
+		Name:        f.Name,
+		Description: f.Description,
+		Options:     f.Options,
+		Stage:       f.Stage != nil,
+		Fn:          f.Fn != nil,
+	}
+}
+
+// StageRegistry holds stage factories by name, so pipelines can be put
+// together from names, e.g. ones read from the config. Stage packages
+// usually register their factories from init. It is safe for concurrent use.
+type StageRegistry[T, D any] struct {
+	mu        sync.RWMutex
+	factories map[string]StageFactory[T, D]
+}
+
+func NewStageRegistry[T, D any]() *StageRegistry[T, D] {
+	return &StageRegistry[T, D]{factories: make(map[string]StageFactory[T, D])}
+}
+
+// Register adds f. Names are unique; registering one twice reports
+// apperror.ErrDuplicateEntry.
+func (r *StageRegistry[T, D]) Register(f StageFactory[T, D]) error {
+	if f.Name == "" || (f.Stage == nil && f.Fn == nil) {
+		return fmt.Errorf("%w: stage factory needs a name and a form", apperror.ErrInvalidInput)
+	}
+	for _, spec := range f.Options {
+		if err := checkKind(spec.Kind, spec.Default); err != nil {
+			return fmt.Errorf("%w: stage %q: option %q: %v",
+				apperror.ErrInvalidInput, f.Name, spec.Name, err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.factories[f.Name]; ok {
+		return fmt.Errorf("%w: stage %q", apperror.ErrDuplicateEntry, f.Name)
+	}
+	r.factories[f.Name] = f
+	return nil
+}
+
+// MustRegister is Register for init functions; it panics on error.
+func (r *StageRegistry[T, D]) MustRegister(f StageFactory[T, D]) {
+	if err := r.Register(f); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the factory registered under name.
+func (r *StageRegistry[T, D]) Lookup(name string) (StageFactory[T, D], bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.factories[name]
+	return f, ok
+}
+
+// Specs lists every registered stage, sorted by name.
+func (r *StageRegistry[T, D]) Specs() []ports.StageSpec {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	specs := make([]ports.StageSpec, 0, len(r.factories))
+	for _, f := range r.factories {
+		specs = append(specs, f.Spec())
+	}
+	slices.SortFunc(specs, func(a, b ports.StageSpec) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return specs
+}
+
+// Stage builds the channel form of the named stage, from its function form
+// if it has no channel form.
+func (r *StageRegistry[T, D]) Stage(
+	name string,
+	deps D,
+	opts map[string]string,
+) (ports.Stage[T], error) {
+	f, parsed, err := r.prepare(name, opts)
+	if err != nil {
+		return nil, err
+	}
+	if f.Stage == nil {
+		fn, err := f.Fn(deps, parsed)
+		if err != nil {
+			return nil, err
+		}
+		return FromFn(name, fn), nil
+	}
+	return f.Stage(deps, parsed)
+}
+
+// Fn builds the function form of the named stage, from its channel form if
+// it has no function form.
+func (r *StageRegistry[T, D]) Fn(
+	name string,
+	deps D,
+	opts map[string]string,
+) (ports.StageFn[T], error) {
+	f, parsed, err := r.prepare(name, opts)
+	if err != nil {
+		return nil, err
+	}
+	if f.Fn == nil {
+		stage, err := f.Stage(deps, parsed)
+		if err != nil {
+			return nil, err
+		}
+		return ToFn(stage), nil
+	}
+	return f.Fn(deps, parsed)
+}
+
+func (r *StageRegistry[T, D]) prepare(
+	name string,
+	opts map[string]string,
+) (StageFactory[T, D], Options, error) {
+	f, ok := r.Lookup(name)
+	if !ok {
+		return f, Options{}, fmt.Errorf("%w: unknown stage %q", apperror.ErrInvalidInput, name)
+	}
+	parsed, err := ParseOptions(f.Options, opts)
+	if err != nil {
+		return f, Options{}, fmt.Errorf("stage %q: %w", name, err)
+	}
+	return f, parsed, nil
+}
+
+var _ ports.StageCatalog = (*StageRegistry[any, any])(nil)
@@ -0,0 +1,154 @@
+package pipelines_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDeps stands in for the dependencies of registered stages.
+type testDeps struct{ offset int }
+
+func newTestRegistry(t *testing.T) *pipelines.StageRegistry[int, testDeps] {
+	t.Helper()
+	r := pipelines.NewStageRegistry[int, testDeps]()
+	require.NoError(t, r.Register(pipelines.StageFactory[int, testDeps]{
+		Name:        "add",
+		Description: "adds the offset dependency and the by option",
+		Options: []ports.OptionSpec{
+			{Name: "by", Kind: ports.OptionInt, Default: "1"},
+			{Name: "wait", Kind: ports.OptionDuration},
+		},
+		Fn: func(deps testDeps, opts pipelines.Options) (ports.StageFn[int], error) {
+			by := deps.offset + opts.Int("by")
+			return func(ctx context.Context, m int) (int, error) { return m + by, nil }, nil
+		},
+	}))
+	require.NoError(t, r.Register(pipelines.StageFactory[int, testDeps]{
+		Name: "even",
+		Stage: func(testDeps, pipelines.Options) (ports.Stage[int], error) {
+			return &rejectStage{name: "even", reject: rejectOdd}, nil
+		},
+	}))
+	return r
+}
+
+func TestStageRegistryBuild(t *testing.T) {
+	r := newTestRegistry(t)
+
+	fn, err := r.Fn("add", testDeps{offset: 10}, map[string]string{"by": "5"})
+	require.NoError(t, err)
+	out, err := fn(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 16, out)
+
+	fn, err = r.Fn("add", testDeps{}, nil)
+	require.NoError(t, err)
+	out, _ = fn(context.Background(), 1)
+	assert.Equal(t, 2, out, "default option")
+
+	stage, err := r.Stage("even", testDeps{}, nil)
+	require.NoError(t, err)
+	items, errs := collect(stage.Run(context.Background(), feed(1, 2)))
+	assert.Equal(t, []int{2}, items)
+	assert.Len(t, errs, 1)
+}
+
+func TestStageRegistryAdaptsForms(t *testing.T) {
+	r := newTestRegistry(t)
+
+	stage, err := r.Stage("add", testDeps{}, nil)
+	require.NoError(t, err)
+	items, errs := collect(stage.Run(context.Background(), feed(1, 2)))
+	assert.Equal(t, []int{2, 3}, items)
+	assert.Empty(t, errs)
+
+	fn, err := r.Fn("even", testDeps{}, nil)
+	require.NoError(t, err)
+	out, err := fn(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, out)
+	_, err = fn(context.Background(), 1)
+	assert.Error(t, err)
+}
+
+func TestStageRegistryErrors(t *testing.T) {
+	r := newTestRegistry(t)
+
+	tests := []struct {
+		name  string
+		build func() error
+		want  error
+	}{
+		{"unknown stage", func() error {
+			_, err := r.Stage("nope", testDeps{}, nil)
+			return err
+		}, apperror.ErrInvalidInput},
+		{"unknown option", func() error {
+			_, err := r.Fn("add", testDeps{}, map[string]string{"times": "2"})
+			return err
+		}, apperror.ErrInvalidInput},
+		{"bad option value", func() error {
+			_, err := r.Fn("add", testDeps{}, map[string]string{"by": "one"})
+			return err
+		}, apperror.ErrInvalidInput},
+		{"duplicate", func() error {
+			return r.Register(pipelines.StageFactory[int, testDeps]{
+				Name: "add",
+				Fn: func(testDeps, pipelines.Options) (ports.StageFn[int], error) {
+					return nil, nil
+				},
+			})
+		}, apperror.ErrDuplicateEntry},
+		{"no form", func() error {
+			return r.Register(pipelines.StageFactory[int, testDeps]{Name: "empty"})
+		}, apperror.ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.build()
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, tt.want), err)
+		})
+	}
+}
+
+func TestStageRegistrySpecs(t *testing.T) {
+	specs := newTestRegistry(t).Specs()
+
+	require.Len(t, specs, 2)
+	assert.Equal(t, "add", specs[0].Name)
+	assert.True(t, specs[0].Fn)
+	assert.False(t, specs[0].Stage)
+	assert.Len(t, specs[0].Options, 2)
+	assert.Equal(t, "even", specs[1].Name)
+	assert.True(t, specs[1].Stage)
+}
+
+func TestParseOptions(t *testing.T) {
+	specs := []ports.OptionSpec{
+		{Name: "topic", Kind: ports.OptionString, Required: true},
+		{Name: "timeout", Kind: ports.OptionDuration, Default: "5s"},
+		{Name: "strict", Kind: ports.OptionBool, Default: "true"},
+	}
+
+	opts, err := pipelines.ParseOptions(specs, map[string]string{"topic": "users"})
+	require.NoError(t, err)
+	assert.Equal(t, "users", opts.String("topic"))
+	assert.Equal(t, 5*time.Second, opts.Duration("timeout"))
+	assert.True(t, opts.Bool("strict"))
+	assert.Zero(t, opts.Int("undeclared"))
+
+	_, err = pipelines.ParseOptions(specs, map[string]string{"strict": "maybe"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `option "topic" is required`)
+	assert.Contains(t, err.Error(), `option "strict"`)
+}
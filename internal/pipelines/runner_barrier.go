@@ -2,63 +2,247 @@ package pipelines
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"go-pipeline/internal/ports"
 )
 
+// ErrPhaseAborted is reported when the error policy stops a barrier run.
+var ErrPhaseAborted = errors.New("barrier phase aborted")
+
+type policyMode int
+
+const (
+	policyContinue policyMode = iota
+	policyFailFast
+	policyThreshold
+)
+
+// ErrorPolicy decides whether a barrier run goes on to the next phase after
+// a phase reported errors.
+type ErrorPolicy struct {
+	mode      policyMode
+	maxErrors int
+	maxRatio  float64
+}
+
+// ContinueOnError keeps running the remaining phases with the items that
+// passed. This is the default policy.
+func ContinueOnError() ErrorPolicy { return ErrorPolicy{mode: policyContinue} }
+
+// FailFast aborts the run as soon as a phase has any error.
+func FailFast() ErrorPolicy { return ErrorPolicy{mode: policyFailFast} }
+
+// AbortAbove aborts the run when a phase has more than maxErrors errors or
+// when more than maxRatio (0..1) of its items failed. A zero value disables
+// the corresponding limit.
+func AbortAbove(maxErrors int, maxRatio float64) ErrorPolicy {
+	return ErrorPolicy{mode: policyThreshold, maxErrors: maxErrors, maxRatio: maxRatio}
+}
+
+// abort reports whether a phase with the given number of input items and
+// errors should stop the run.
+func (p ErrorPolicy) abort(items, failures int) bool {
+	switch p.mode {
+	case policyFailFast:
+		return failures > 0
+	case policyThreshold:
+		if p.maxErrors > 0 && failures > p.maxErrors {
+			return true
+		}
+		return p.maxRatio > 0 && items > 0 && float64(failures)/float64(items) > p.maxRatio
+	default:
+		return false
+	}
+}
+
+// RunnerBarrier runs its stages as phases: every phase has to finish with
+// all items before the next one starts. The errors of every phase are
+// collected and returned once the run is over; the ErrorPolicy decides
+// whether a phase with errors stops the run.
 type RunnerBarrier[T any] struct {
-	stages  []ports.Stage[T]
-	buffCap int
+	stages     []ports.Stage[T]
+	buffCap    int
+	deadLetter ports.DeadLetterSink[T]
+	policy     ErrorPolicy
+	timeout    time.Duration
+	buffers    *BufferPlan
+	ics        []Interceptor
+	listeners  []ports.RunListener
 }
 
 func NewRunnerBarrier[T any](buffCap int, st ...ports.Stage[T]) *RunnerBarrier[T] {
 	return &RunnerBarrier[T]{
 		stages:  st,
 		buffCap: buffCap,
+		policy:  ContinueOnError(),
 	}
 }
 
+// WithDeadLetter routes every item that fails a stage to sink.
+func (r *RunnerBarrier[T]) WithDeadLetter(sink ports.DeadLetterSink[T]) *RunnerBarrier[T] {
+	r.deadLetter = sink
+	return r
+}
+
+// WithErrorPolicy sets the policy applied after every phase.
+func (r *RunnerBarrier[T]) WithErrorPolicy(p ErrorPolicy) *RunnerBarrier[T] {
+	r.policy = p
+	return r
+}
+
+// WithBuffers sets the channel buffers every stage gets through
+// BuffersFrom.
+func (r *RunnerBarrier[T]) WithBuffers(plan BufferPlan) *RunnerBarrier[T] {
+	r.buffers = &plan
+	return r
+}
+
+// WithInterceptors wraps every Process call of the item stages with ics;
+// other stages are not intercepted.
+func (r *RunnerBarrier[T]) WithInterceptors(ics ...Interceptor) *RunnerBarrier[T] {
+	r.ics = ics
+	return r
+}
+
+// WithListeners adds listeners that observe every run.
+func (r *RunnerBarrier[T]) WithListeners(ls ...ports.RunListener) *RunnerBarrier[T] {
+	r.listeners = append(r.listeners, ls...)
+	return r
+}
+
+// WithTimeout bounds every run to d, including the time spent gathering the
+// input. A run cut short by the deadline reports apperror.ErrTimeout.
+func (r *RunnerBarrier[T]) WithTimeout(d time.Duration) *RunnerBarrier[T] {
+	r.timeout = d
+	return r
+}
+
 func (r *RunnerBarrier[T]) Run(ctx context.Context, in <-chan T) (<-chan T, <-chan error) {
-	curIn := in
-	allErrs := make([]<-chan error, len(r.stages))
+	if r.timeout <= 0 {
+		return r.run(ctx, in)
+	}
+	tctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	out, errCh := r.run(tctx, in)
+	var errs []error
+	for err := range errCh {
+		errs = append(errs, overrun(ctx, tctx, "pipeline", r.timeout, err))
+	}
+	return out, emitAll(errs)
+}
+
+func (r *RunnerBarrier[T]) run(ctx context.Context, in <-chan T) (<-chan T, <-chan error) {
+	var allErrs []error
+	obs := startRun(ctx, r.listeners)
+
+	cur, err := r.gather(ctx, in)
+	if err != nil {
+		return finishPhases[T](ctx, obs, 0, nil, nil, err)
+	}
+	inputs := len(cur)
 
 	for _, stage := range r.stages {
-		out, errChan := stage.Run(ctx, curIn)
-		allErrs = append(allErrs, errChan)
-
-		buffer := make([]T, 0, r.buffCap)
-		drainDone := false
-
-		for !drainDone {
-			select {
-			case <-ctx.Done():
-				ch := make(chan T)
-				close(ch)
-				errCh := make(chan error)
-				close(errCh)
-				return ch, errCh
-			case m, ok := <-out:
-				if !ok {
-					drainDone = true
-					continue
-				}
-				buffer = append(buffer, m)
-			case e, ok := <-errChan:
-				if !ok {
-					errChan = nil
-					continue
-				}
-				_ = e
+		start := time.Now()
+		phase := observe(obs, isolate(stage, r.ics...))
+		next, phaseErrs, errPhase := r.runPhase(ctx, phase, cur)
+		obs.phaseComplete(ctx, stage.Name(), len(cur), len(next), len(phaseErrs), time.Since(start))
+		allErrs = append(allErrs, phaseErrs...)
+		if errPhase != nil {
+			return finishPhases[T](ctx, obs, inputs, nil, allErrs, errPhase)
+		}
+		if r.policy.abort(len(cur), len(phaseErrs)) {
+			errAbort := fmt.Errorf("%w: stage %s failed %d of %d items",
+				ErrPhaseAborted, stage.Name(), len(phaseErrs), len(cur))
+			return finishPhases[T](ctx, obs, inputs, nil, allErrs, errAbort)
+		}
+		cur = next
+	}
+	return finishPhases(ctx, obs, inputs, cur, allErrs, nil)
+}
+
+// finishPhases reports the end of a barrier run and emits its results;
+// errEnd is what ended the run early, if anything.
+func finishPhases[T any](
+	ctx context.Context,
+	obs *runObserver,
+	inputs int,
+	out []T,
+	errs []error,
+	errEnd error,
+) (<-chan T, <-chan error) {
+	if errEnd != nil {
+		errs = append(errs, errEnd)
+	}
+	obs.count(inputs, len(out), len(errs))
+	obs.end(ctx, errEnd)
+	return emitAll(out), emitAll(errs)
+}
+
+// gather waits for the whole input, so the first phase starts behind a
+// barrier like every other phase.
+func (r *RunnerBarrier[T]) gather(ctx context.Context, in <-chan T) ([]T, error) {
+	buffer := make([]T, 0, r.buffCap)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case m, ok := <-in:
+			if !ok {
+				return buffer, nil
 			}
+			buffer = append(buffer, m)
 		}
-		nextIn := make(chan T, len(buffer))
-		for _, v := range buffer {
-			nextIn <- v
+	}
+}
+
+// runPhase runs one stage over all items and waits until both of its
+// channels are closed. It only returns an error when ctx is done.
+func (r *RunnerBarrier[T]) runPhase(
+	ctx context.Context,
+	stage ports.Stage[T],
+	items []T,
+) ([]T, []error, error) {
+	out, errChan := stage.Run(stageContext(ctx, r.buffers, stage.Name()), emitAll(items))
+	errChan = tagErrors[T](stage.Name(), errChan)
+	if r.deadLetter != nil {
+		errChan = routeDeadLetters(ctx, r.deadLetter, errChan)
+	}
+
+	buffer := make([]T, 0, r.buffCap)
+	var errs []error
+	for out != nil || errChan != nil {
+		select {
+		case <-ctx.Done():
+			return nil, errs, ctx.Err()
+		case m, ok := <-out:
+			if !ok {
+				out = nil
+				continue
+			}
+			buffer = append(buffer, m)
+		case e, ok := <-errChan:
+			if !ok {
+				errChan = nil
+				continue
+			}
+			errs = append(errs, e)
 		}
-		close(nextIn)
-		curIn = nextIn
 	}
-	return curIn, mergeErrors(allErrs...)
+	return buffer, errs, nil
+}
+
+// emitAll returns a closed channel holding all values.
+func emitAll[T any](values []T) <-chan T {
+	ch := make(chan T, len(values))
+	for _, v := range values {
+		ch <- v
+	}
+	close(ch)
+	return ch
 }
 
 var _ ports.BarrierPipeLine[any] = (*RunnerBarrier[any])(nil)
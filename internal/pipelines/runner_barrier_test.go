@@ -0,0 +1,53 @@
+package pipelines_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunnerBarrierErrorPolicy(t *testing.T) {
+	rejectAbove := func(limit int) func(int) error {
+		return func(m int) error {
+			if m > limit {
+				return apperror.ErrInvalidInput
+			}
+			return nil
+		}
+	}
+
+	tests := []struct {
+		name      string
+		policy    pipelines.ErrorPolicy
+		wantItems []int
+		wantErrs  int
+		aborted   bool
+	}{
+		{"Continue", pipelines.ContinueOnError(), []int{1}, 3, false},
+		{"FailFast", pipelines.FailFast(), nil, 2, true},
+		{"ThresholdCountExceeded", pipelines.AbortAbove(1, 0), nil, 4, true},
+		{"ThresholdCountNotExceeded", pipelines.AbortAbove(2, 0), []int{1}, 3, false},
+		{"ThresholdRatioExceeded", pipelines.AbortAbove(0, 0.5), nil, 4, true},
+		{"ThresholdRatioNotExceeded", pipelines.AbortAbove(0, 0.7), []int{1}, 3, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := pipelines.NewRunnerBarrier[int](4,
+				&rejectStage{name: "le3", reject: rejectAbove(3)},
+				&rejectStage{name: "le1", reject: rejectAbove(1)},
+			).WithErrorPolicy(tt.policy)
+
+			items, errs := collect(runner.Run(context.Background(), feed(1, 2, 3, 4)))
+
+			assert.Equal(t, tt.wantItems, items)
+			assert.Len(t, errs, tt.wantErrs)
+			assert.Equal(t, tt.aborted, errors.Is(errors.Join(errs...), pipelines.ErrPhaseAborted))
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package pipelines
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+)
+
+// broadcastBuffer is the capacity of every fan-out copy of a stage output.
+const broadcastBuffer = 16
+
+// DAGNode is a stage of a RunnerDAG. Nodes are identified by their stage
+// name and list the names of the nodes they consume from in After.
+// Nodes without upstream nodes are roots and consume the run's input, nodes
+// without downstream nodes are leaves and make up the run's output, unless
+// they Discard it, e.g. a side branch that only stores the items.
+type DAGNode[T any] struct {
+	Stage   ports.Stage[T]
+	After   []string
+	Discard bool
+}
+
+// Node is a shorthand for building a DAGNode.
+func Node[T any](stage ports.Stage[T], after ...string) DAGNode[T] {
+	return DAGNode[T]{Stage: stage, After: after}
+}
+
+// DiscardNode is a shorthand for building a leaf DAGNode whose outputs are
+// dropped; only its errors reach the run.
+func DiscardNode[T any](stage ports.Stage[T], after ...string) DAGNode[T] {
+	return DAGNode[T]{Stage: stage, After: after, Discard: true}
+}
+
+// RunnerDAG runs stages arranged as a directed acyclic graph.
+// The graph is validated when the runner is built: node names must be
+// unique, every upstream node must exist and cycles are rejected.
+type RunnerDAG[T any] struct {
+	nodes      []DAGNode[T]
+	order      []int   // node indexes in topological order
+	children   [][]int // downstream node indexes per node
+	deadLetter ports.DeadLetterSink[T]
+	timeout    time.Duration
+	buffers    *BufferPlan
+	ics        []Interceptor
+}
+
+// NewRunnerDAG validates the graph and returns a runner for it.
+func NewRunnerDAG[T any](nodes ...DAGNode[T]) (*RunnerDAG[T], error) {
+	index := make(map[string]int, len(nodes))
+	for i, n := range nodes {
+		name := n.Stage.Name()
+		if _, dup := index[name]; dup {
+			return nil, fmt.Errorf("%w: dag: duplicate node %q", apperror.ErrInvalidInput, name)
+		}
+		index[name] = i
+	}
+
+	children := make([][]int, len(nodes))
+	indegree := make([]int, len(nodes))
+	for i, n := range nodes {
+		for _, up := range n.After {
+			j, ok := index[up]
+			if !ok {
+				return nil, fmt.Errorf("%w: dag: node %q depends on unknown node %q",
+					apperror.ErrInvalidInput, n.Stage.Name(), up)
+			}
+			children[j] = append(children[j], i)
+			indegree[i]++
+		}
+	}
+
+	for i, n := range nodes {
+		if n.Discard && len(children[i]) > 0 {
+			return nil, fmt.Errorf("%w: dag: node %q discards its outputs but has downstream nodes",
+				apperror.ErrInvalidInput, n.Stage.Name())
+		}
+	}
+
+	order, err := topoSort(nodes, children, indegree)
+	if err != nil {
+		return nil, err
+	}
+	return &RunnerDAG[T]{nodes: nodes, order: order, children: children}, nil
+}
+
+// topoSort orders the nodes with Kahn's algorithm and reports the nodes
+// left over when the graph has a cycle.
+func topoSort[T any](nodes []DAGNode[T], children [][]int, indegree []int) ([]int, error) {
+	indegree = slices.Clone(indegree)
+	order := make([]int, 0, len(nodes))
+	for i, d := range indegree {
+		if d == 0 {
+			order = append(order, i)
+		}
+	}
+	for k := 0; k < len(order); k++ {
+		for _, c := range children[order[k]] {
+			indegree[c]--
+			if indegree[c] == 0 {
+				order = append(order, c)
+			}
+		}
+	}
+	if len(order) == len(nodes) {
+		return order, nil
+	}
+
+	var cyclic []string
+	for i, d := range indegree {
+		if d > 0 {
+			cyclic = append(cyclic, nodes[i].Stage.Name())
+		}
+	}
+	return nil, fmt.Errorf("%w: dag: cycle between nodes %v", apperror.ErrInvalidInput, cyclic)
+}
+
+// WithDeadLetter routes every item that fails a stage to sink.
+func (r *RunnerDAG[T]) WithDeadLetter(sink ports.DeadLetterSink[T]) *RunnerDAG[T] {
+	r.deadLetter = sink
+	return r
+}
+
+// WithBuffers sets the channel buffers every stage gets through
+// BuffersFrom.
+func (r *RunnerDAG[T]) WithBuffers(plan BufferPlan) *RunnerDAG[T] {
+	r.buffers = &plan
+	return r
+}
+
+// WithInterceptors wraps every Process call of the item stages with ics;
+// other stages are not intercepted.
+func (r *RunnerDAG[T]) WithInterceptors(ics ...Interceptor) *RunnerDAG[T] {
+	r.ics = ics
+	return r
+}
+
+// WithTimeout bounds every run to d. Stages see the deadline through their
+// context; a run cut short by it reports apperror.ErrTimeout.
+func (r *RunnerDAG[T]) WithTimeout(d time.Duration) *RunnerDAG[T] {
+	r.timeout = d
+	return r
+}
+
+func (r *RunnerDAG[T]) Run(ctx context.Context, in <-chan T) (<-chan T, <-chan error) {
+	return runWithTimeout(ctx, r.timeout, in, r.run)
+}
+
+func (r *RunnerDAG[T]) run(ctx context.Context, in <-chan T) (<-chan T, <-chan error) {
+	inputs := make([][]<-chan T, len(r.nodes))
+
+	var roots []int
+	for i, n := range r.nodes {
+		if len(n.After) == 0 {
+			roots = append(roots, i)
+		}
+	}
+	for k, c := range broadcast(ctx, in, len(roots)) {
+		inputs[roots[k]] = append(inputs[roots[k]], c)
+	}
+
+	var leaves []<-chan T
+	errs := make([]<-chan error, 0, len(r.nodes))
+	for _, i := range r.order {
+		stage := isolate(r.nodes[i].Stage, r.ics...)
+		src := inputs[i][0]
+		if len(inputs[i]) > 1 {
+			src = fanIn(ctx, inputs[i]...)
+		}
+
+		out, errCh := stage.Run(stageContext(ctx, r.buffers, stage.Name()), src)
+		errCh = tagErrors[T](stage.Name(), errCh)
+		if r.deadLetter != nil {
+			errCh = routeDeadLetters(ctx, r.deadLetter, errCh)
+		}
+		errs = append(errs, errCh)
+
+		if len(r.children[i]) == 0 {
+			if r.nodes[i].Discard {
+				go func() {
+					for range out {
+					}
+				}()
+				continue
+			}
+			leaves = append(leaves, out)
+			continue
+		}
+		for k, c := range broadcast(ctx, out, len(r.children[i])) {
+			child := r.children[i][k]
+			inputs[child] = append(inputs[child], c)
+		}
+	}
+	return fanIn(ctx, leaves...), mergeErrors(errs...)
+}
+
+// broadcast copies every value of src to n channels. After ctx is done the
+// remaining values are drained and dropped so the producer never blocks.
+func broadcast[T any](ctx context.Context, src <-chan T, n int) []<-chan T {
+	if n == 1 {
+		return []<-chan T{src}
+	}
+	outs := make([]chan T, n)
+	res := make([]<-chan T, n)
+	for i := range outs {
+		outs[i] = make(chan T, broadcastBuffer)
+		res[i] = outs[i]
+	}
+	go func() {
+		defer func() {
+			for _, o := range outs {
+				close(o)
+			}
+		}()
+		for v := range src {
+			for _, o := range outs {
+				select {
+				case o <- v:
+				case <-ctx.Done():
+				}
+			}
+		}
+	}()
+	return res
+}
+
+var _ ports.DAGPipeLine[any] = (*RunnerDAG[any])(nil)
@@ -0,0 +1,91 @@
+package pipelines_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pass(name string) *rejectStage {
+	return &rejectStage{name: name, reject: func(int) error { return nil }}
+}
+
+func TestRunnerDAG(t *testing.T) {
+	// a -> b -> d, a -> c -> d, and c drops odd items
+	runner, err := pipelines.NewRunnerDAG(
+		pipelines.Node[int](pass("d"), "b", "c"),
+		pipelines.Node[int](pass("b"), "a"),
+		pipelines.Node[int](&rejectStage{name: "c", reject: rejectOdd}, "a"),
+		pipelines.Node[int](pass("a")),
+	)
+	require.NoError(t, err)
+
+	items, errs := collect(runner.Run(context.Background(), feed(1, 2)))
+	sort.Ints(items)
+
+	assert.Equal(t, []int{1, 2, 2}, items)
+	require.Len(t, errs, 1)
+	se, ok := apperror.AsStageError[int](errs[0])
+	require.True(t, ok)
+	assert.Equal(t, "c", se.Stage)
+	assert.Equal(t, 1, se.Item)
+}
+
+func TestRunnerDAGDiscard(t *testing.T) {
+	// a -> b, and the side branch a -> c drops its outputs but not its errors
+	runner, err := pipelines.NewRunnerDAG(
+		pipelines.Node[int](pass("a")),
+		pipelines.Node[int](pass("b"), "a"),
+		pipelines.DiscardNode[int](&rejectStage{name: "c", reject: rejectOdd}, "a"),
+	)
+	require.NoError(t, err)
+
+	items, errs := collect(runner.Run(context.Background(), feed(1, 2)))
+	sort.Ints(items)
+
+	assert.Equal(t, []int{1, 2}, items)
+	require.Len(t, errs, 1)
+	se, ok := apperror.AsStageError[int](errs[0])
+	require.True(t, ok)
+	assert.Equal(t, "c", se.Stage)
+}
+
+func TestNewRunnerDAGRejectsInvalidGraphs(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []pipelines.DAGNode[int]
+	}{
+		{"Cycle", []pipelines.DAGNode[int]{
+			pipelines.Node[int](pass("a")),
+			pipelines.Node[int](pass("b"), "a", "c"),
+			pipelines.Node[int](pass("c"), "b"),
+		}},
+		{"SelfLoop", []pipelines.DAGNode[int]{
+			pipelines.Node[int](pass("a"), "a"),
+		}},
+		{"UnknownNode", []pipelines.DAGNode[int]{
+			pipelines.Node[int](pass("a"), "missing"),
+		}},
+		{"DuplicateNode", []pipelines.DAGNode[int]{
+			pipelines.Node[int](pass("a")),
+			pipelines.Node[int](pass("a")),
+		}},
+		{"DiscardWithChildren", []pipelines.DAGNode[int]{
+			pipelines.DiscardNode[int](pass("a")),
+			pipelines.Node[int](pass("b"), "a"),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pipelines.NewRunnerDAG(tt.nodes...)
+			assert.ErrorIs(t, err, apperror.ErrInvalidInput)
+		})
+	}
+}
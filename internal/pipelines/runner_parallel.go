@@ -3,25 +3,73 @@ package pipelines
 import (
 	"context"
 	"sync"
+	"time"
 
 	"go-pipeline/internal/ports"
 )
 
 type Runner[T any] struct {
-	stages []ports.Stage[T]
+	stages     []ports.Stage[T]
+	deadLetter ports.DeadLetterSink[T]
+	timeout    time.Duration
+	buffers    *BufferPlan
+	ics        []Interceptor
+	listeners  []ports.RunListener
 }
 
 func NewRunner[T any](stages ...ports.Stage[T]) *Runner[T] { return &Runner[T]{stages: stages} }
 
+// WithDeadLetter routes every item that fails a stage to sink.
+func (r *Runner[T]) WithDeadLetter(sink ports.DeadLetterSink[T]) *Runner[T] {
+	r.deadLetter = sink
+	return r
+}
+
+// WithBuffers sets the channel buffers every stage gets through
+// BuffersFrom.
+func (r *Runner[T]) WithBuffers(plan BufferPlan) *Runner[T] {
+	r.buffers = &plan
+	return r
+}
+
+// WithInterceptors wraps every Process call of the item stages with ics;
+// other stages are not intercepted.
+func (r *Runner[T]) WithInterceptors(ics ...Interceptor) *Runner[T] {
+	r.ics = ics
+	return r
+}
+
+// WithListeners adds listeners that observe every run.
+func (r *Runner[T]) WithListeners(ls ...ports.RunListener) *Runner[T] {
+	r.listeners = append(r.listeners, ls...)
+	return r
+}
+
+// WithTimeout bounds every Chain call to d. Stages see the deadline through
+// their context; a run cut short by it reports apperror.ErrTimeout.
+func (r *Runner[T]) WithTimeout(d time.Duration) *Runner[T] {
+	r.timeout = d
+	return r
+}
+
 func (r *Runner[T]) Chain(ctx context.Context, in <-chan T) (out <-chan T, errMerged <-chan error) {
-	cur := in
+	return runWithTimeout(ctx, r.timeout, in, r.chain)
+}
+
+func (r *Runner[T]) chain(ctx context.Context, in <-chan T) (<-chan T, <-chan error) {
+	run := startRun(ctx, r.listeners)
+	cur := observeRun(ctx, run, in)
 	errs := make([]<-chan error, len(r.stages))
 	for i, s := range r.stages {
-		o, e := s.Run(ctx, cur)
+		s = observe(run, isolate(s, r.ics...))
+		o, e := s.Run(stageContext(ctx, r.buffers, s.Name()), cur)
 		cur = o
-		errs[i] = e
+		errs[i] = tagErrors[T](s.Name(), e)
+		if r.deadLetter != nil {
+			errs[i] = routeDeadLetters(ctx, r.deadLetter, errs[i])
+		}
 	}
-	return cur, mergeErrors(errs...)
+	return finishRun(ctx, run, cur, mergeErrors(errs...))
 }
 
 var _ ports.ChainPipeline[any] = (*Runner[any])(nil)
@@ -2,28 +2,195 @@ package pipelines
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
 
 	"go-pipeline/internal/ports"
 )
 
 type RunnerShortCircuit[T any] struct {
-	stages []ports.StageFn[T]
+	steps      []Step[T]
+	deadLetter ports.DeadLetterSink[T]
+	timeout    time.Duration
+	ics        []Interceptor
+	listeners  []ports.RunListener
 }
 
 func NewRunnerShortCircuit[T any](stages ...ports.StageFn[T]) *RunnerShortCircuit[T] {
+	steps := make([]Step[T], len(stages))
+	for i, fn := range stages {
+		steps[i] = Step[T]{Fn: fn}
+	}
+	return NewRunnerShortCircuitSteps(steps...)
+}
+
+// NewRunnerShortCircuitSteps creates a short-circuit runner from named steps
+// that may register compensations. When a step fails, the compensations of
+// all completed steps run in reverse order and their outcome is reported in
+// a *SagaError. Steps without a name are called "stage_<index>";
+// steps itself is left as it is.
+func NewRunnerShortCircuitSteps[T any](steps ...Step[T]) *RunnerShortCircuit[T] {
+	steps = slices.Clone(steps)
+	for i := range steps {
+		if steps[i].Name == "" {
+			steps[i].Name = fmt.Sprintf("stage_%d", i)
+		}
+	}
 	return &RunnerShortCircuit[T]{
-		stages: stages,
+		steps: steps,
 	}
 }
 
+// WithDeadLetter routes the item that stopped a run to sink.
+func (r *RunnerShortCircuit[T]) WithDeadLetter(
+	sink ports.DeadLetterSink[T],
+) *RunnerShortCircuit[T] {
+	r.deadLetter = sink
+	return r
+}
+
+// WithTimeout bounds every run to d. Steps see the deadline through their
+// context, and no step starts once it has passed; the run then fails with
+// apperror.ErrTimeout.
+func (r *RunnerShortCircuit[T]) WithTimeout(d time.Duration) *RunnerShortCircuit[T] {
+	r.timeout = d
+	return r
+}
+
+// WithInterceptors wraps every step call with ics.
+func (r *RunnerShortCircuit[T]) WithInterceptors(ics ...Interceptor) *RunnerShortCircuit[T] {
+	r.ics = ics
+	return r
+}
+
+// WithListeners adds listeners that observe every run.
+func (r *RunnerShortCircuit[T]) WithListeners(ls ...ports.RunListener) *RunnerShortCircuit[T] {
+	r.listeners = append(r.listeners, ls...)
+	return r
+}
+
+// Run passes m through the steps. A step that drops m, by returning
+// ErrDropped, ends the run successfully: the steps before it are not
+// compensated and m is returned as that step got it.
 func (r *RunnerShortCircuit[T]) Run(ctx context.Context, m T) (T, error) {
+	out, _, err := r.run(ctx, m, nil, false)
+	return out, err
+}
+
+func (r *RunnerShortCircuit[T]) RunTraced(
+	ctx context.Context,
+	m T,
+	debug bool,
+) (T, []ports.StageTrace[T], error) {
+	trace := make([]ports.StageTrace[T], 0, len(r.steps))
+	out, _, err := r.run(ctx, m, &trace, debug)
+	return out, trace, err
+}
+
+// run passes the value from step to step and reports whether it went
+// through all of them rather than being dropped. When trace is not nil, one
+// entry per executed step is appended to it.
+func (r *RunnerShortCircuit[T]) run(
+	ctx context.Context,
+	m T,
+	trace *[]ports.StageTrace[T],
+	debug bool,
+) (T, bool, error) {
+	runCtx := ctx
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	obs := startRun(ctx, r.listeners)
 	cur := m
-	for _, stage := range r.stages {
-		next, err := stage(ctx, m)
+	done := make([]completedStep[T], 0, len(r.steps))
+	for _, step := range r.steps {
+		start := time.Now()
+		var c counts
+		obs.itemIn(ctx, step.Name, &c)
+		next, err := call(runCtx, step, cur, r.ics)
 		if err != nil {
-			return cur, err
+			err = overrun(ctx, runCtx, "pipeline", r.timeout, err)
 		}
+		if trace != nil {
+			*trace = append(*trace, stageTrace(step.Name, start, cur, next, err, debug))
+		}
+		if errors.Is(err, ErrDropped) {
+			obs.count(1, 0, 0)
+			obs.end(ctx, nil)
+			return cur, false, nil
+		}
+		if err != nil {
+			obs.stageError(ctx, step.Name, &c, err)
+			err = r.fail(ctx, done, withStage(step.Name, cur, 1, err))
+			obs.count(1, 0, 1)
+			obs.end(ctx, err)
+			return cur, false, err
+		}
+		obs.itemOut(ctx, step.Name, &c)
 		cur = next
+		done = append(done, completedStep[T]{step: step, out: next})
+	}
+	obs.count(1, 1, 0)
+	obs.end(ctx, nil)
+	return cur, true, nil
+}
+
+// call runs step through ics unless ctx is already done. A panic fails the
+// step with a *PanicError, which the interceptors see as its error.
+func call[T any](ctx context.Context, step Step[T], m T, ics []Interceptor) (T, error) {
+	if err := ctx.Err(); err != nil {
+		return m, err
+	}
+	fn := func(ctx context.Context, m T) (T, error) {
+		return recovered(step.Name, m, func() (T, error) { return step.Fn(ctx, m) })
 	}
-	return cur, nil
+	// an interceptor may panic as well
+	return recovered(step.Name, m, func() (T, error) {
+		return InterceptFn(step.Name, fn, ics...)(ctx, m)
+	})
 }
+
+// fail compensates the completed steps and dead-letters the failed item.
+func (r *RunnerShortCircuit[T]) fail(
+	ctx context.Context,
+	done []completedStep[T],
+	err error,
+) error {
+	err = compensate(ctx, done, err)
+	if r.deadLetter != nil {
+		if errDL := deadLetter(ctx, r.deadLetter, err); errDL != nil {
+			err = errors.Join(err, errDL)
+		}
+	}
+	return err
+}
+
+func stageTrace[T any](
+	name string,
+	start time.Time,
+	in, out T,
+	err error,
+	debug bool,
+) ports.StageTrace[T] {
+	st := ports.StageTrace[T]{
+		Stage:    name,
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		st.Error = err.Error()
+	}
+	if debug {
+		st.Input = &in
+		if err == nil {
+			st.Output = &out
+		}
+	}
+	return st
+}
+
+var _ ports.ShortCircuitPipeLine[any] = (*RunnerShortCircuit[any])(nil)
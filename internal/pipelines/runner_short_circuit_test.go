@@ -0,0 +1,110 @@
+package pipelines_test
+
+import (
+	"context"
+	"testing"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunnerShortCircuitThreadsValue(t *testing.T) {
+	var sunk int
+	runner := pipelines.NewRunnerShortCircuitSteps(
+		pipelines.Step[int]{Name: "double", Fn: func(ctx context.Context, m int) (int, error) {
+			return m * 2, nil
+		}},
+		pipelines.Step[int]{Name: "sink", Fn: func(ctx context.Context, m int) (int, error) {
+			sunk = m
+			return m, nil
+		}},
+	)
+
+	out, err := runner.Run(context.Background(), 21)
+
+	require.NoError(t, err)
+	assert.Equal(t, 42, out)
+	assert.Equal(t, 42, sunk)
+}
+
+func TestRunnerShortCircuitDrop(t *testing.T) {
+	dropOdd := pipelines.Filter("odd", func(m int) bool { return m%2 == 0 })
+	var rolledBack, sunk []int
+	sink := pipelines.NewMemoryDeadLetterSink[int]()
+	runner := pipelines.NewRunnerShortCircuitSteps(
+		pipelines.Step[int]{
+			Name: "store",
+			Fn:   func(ctx context.Context, m int) (int, error) { return m, nil },
+			Compensate: func(ctx context.Context, m int) error {
+				rolledBack = append(rolledBack, m)
+				return nil
+			},
+		},
+		pipelines.Step[int]{Name: "odd", Fn: pipelines.ToFn(dropOdd)},
+		pipelines.Step[int]{Name: "sink", Fn: func(ctx context.Context, m int) (int, error) {
+			sunk = append(sunk, m)
+			return m, nil
+		}},
+	).WithDeadLetter(sink)
+
+	out, err := runner.Run(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, out)
+
+	items, errs := runner.Process(context.Background(), []int{1, 2, 3})
+	assert.Equal(t, []int{2}, items)
+	assert.Empty(t, errs)
+
+	assert.Equal(t, []int{2}, sunk)
+	assert.Empty(t, rolledBack)
+	assert.Empty(t, sink.Letters())
+}
+
+func TestRunnerShortCircuitTrace(t *testing.T) {
+	steps := []pipelines.Step[int]{
+		{Name: "inc", Fn: func(ctx context.Context, m int) (int, error) {
+			return m + 1, nil
+		}},
+		{Fn: func(ctx context.Context, m int) (int, error) {
+			return m, apperror.ErrInvalidInput
+		}},
+		{Name: "never", Fn: func(ctx context.Context, m int) (int, error) {
+			return m, nil
+		}},
+	}
+	runner := pipelines.NewRunnerShortCircuitSteps(steps...)
+	assert.Empty(t, steps[1].Name, "the default name must not leak into the caller's steps")
+
+	tests := []struct {
+		name  string
+		debug bool
+	}{
+		{"Plain", false},
+		{"Debug", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, trace, err := runner.RunTraced(context.Background(), 1, tt.debug)
+
+			assert.ErrorIs(t, err, apperror.ErrInvalidInput)
+			require.Len(t, trace, 2)
+			assert.Equal(t, "inc", trace[0].Stage)
+			assert.Equal(t, "stage_1", trace[1].Stage)
+			assert.Empty(t, trace[0].Error)
+			assert.NotEmpty(t, trace[1].Error)
+			if !tt.debug {
+				assert.Nil(t, trace[0].Input)
+				assert.Nil(t, trace[0].Output)
+				return
+			}
+			assert.Equal(t, 1, *trace[0].Input)
+			assert.Equal(t, 2, *trace[0].Output)
+			assert.Equal(t, 2, *trace[1].Input)
+			assert.Nil(t, trace[1].Output)
+		})
+	}
+}
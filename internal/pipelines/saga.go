@@ -0,0 +1,69 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-pipeline/internal/ports"
+)
+
+// Step is a named stage of a short-circuit pipeline with an optional
+// compensation that is run when a later step fails.
+type Step[T any] struct {
+	Name       string
+	Fn         ports.StageFn[T]
+	Compensate ports.Compensator[T]
+}
+
+// SagaError is returned by RunnerShortCircuit when a stage fails after
+// earlier stages with compensations had completed. It wraps the stage error
+// and reports which compensations ran and which of them failed.
+type SagaError struct {
+	Err         error    // the stage failure that triggered the rollback
+	Compensated []string // stages rolled back successfully, in rollback order
+	Failed      []error  // compensations that returned an error
+}
+
+func (e *SagaError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v; compensated [%s]", e.Err, strings.Join(e.Compensated, ", "))
+	if len(e.Failed) > 0 {
+		fmt.Fprintf(&b, "; %d compensation(s) failed: %v", len(e.Failed), errors.Join(e.Failed...))
+	}
+	return b.String()
+}
+
+func (e *SagaError) Unwrap() []error { return append([]error{e.Err}, e.Failed...) }
+
+// completedStep remembers a finished step and the value it returned.
+type completedStep[T any] struct {
+	step Step[T]
+	out  T
+}
+
+// compensate rolls completed steps back in reverse order. Compensations run
+// even when ctx is already canceled, since they clean up after a failure.
+// It returns err unchanged when no completed step has a compensation.
+func compensate[T any](ctx context.Context, done []completedStep[T], err error) error {
+	ctx = context.WithoutCancel(ctx)
+	saga := &SagaError{Err: err}
+	ran := false
+	for i := len(done) - 1; i >= 0; i-- {
+		c := done[i]
+		if c.step.Compensate == nil {
+			continue
+		}
+		ran = true
+		if errC := c.step.Compensate(ctx, c.out); errC != nil {
+			saga.Failed = append(saga.Failed, fmt.Errorf("compensate %s: %w", c.step.Name, errC))
+			continue
+		}
+		saga.Compensated = append(saga.Compensated, c.step.Name)
+	}
+	if !ran {
+		return err
+	}
+	return saga
+}
@@ -0,0 +1,57 @@
+package pipelines_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunnerShortCircuitCompensation(t *testing.T) {
+	var rolledBack []string
+	undo := func(name string, err error) func(context.Context, int) error {
+		return func(ctx context.Context, m int) error {
+			rolledBack = append(rolledBack, name)
+			return err
+		}
+	}
+	ok := func(ctx context.Context, m int) (int, error) { return m, nil }
+	fail := func(ctx context.Context, m int) (int, error) { return m, apperror.ErrUnavailable }
+	errUndo := errors.New("cannot delete record")
+
+	runner := pipelines.NewRunnerShortCircuitSteps(
+		pipelines.Step[int]{Fn: ok, Compensate: undo("store", nil)},
+		pipelines.Step[int]{Fn: ok},
+		pipelines.Step[int]{Fn: ok, Compensate: undo("produce", errUndo)},
+		pipelines.Step[int]{Fn: fail},
+	)
+
+	_, err := runner.Run(context.Background(), 1)
+
+	assert.Equal(t, []string{"produce", "store"}, rolledBack)
+	var saga *pipelines.SagaError
+	require.ErrorAs(t, err, &saga)
+	assert.Equal(t, []string{"stage_0"}, saga.Compensated)
+	assert.Len(t, saga.Failed, 1)
+	assert.ErrorIs(t, err, apperror.ErrUnavailable)
+	assert.ErrorIs(t, err, errUndo)
+	_, isStageErr := apperror.AsStageError[int](err)
+	assert.True(t, isStageErr)
+}
+
+func TestRunnerShortCircuitWithoutCompensation(t *testing.T) {
+	runner := pipelines.NewRunnerShortCircuit(
+		func(ctx context.Context, m int) (int, error) { return m, apperror.ErrInvalidInput },
+	)
+
+	_, err := runner.Run(context.Background(), 1)
+
+	var saga *pipelines.SagaError
+	assert.False(t, errors.As(err, &saga))
+	assert.ErrorIs(t, err, apperror.ErrInvalidInput)
+}
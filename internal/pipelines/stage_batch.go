@@ -0,0 +1,137 @@
+package pipelines
+
+import (
+	"context"
+	"time"
+
+	"go-pipeline/internal/ports"
+)
+
+// BatchStage groups items into slices. A batch is emitted as soon as it holds
+// `size` items or `maxWait` after its first item arrived, whichever comes
+// first, so bulk consumers (DB inserts, batched sends) get full batches under
+// load and bounded latency when traffic is low.
+//
+// When the input closes, the partial batch is flushed. When ctx is canceled,
+// the partial batch is still handed over if the output buffer has room.
+type BatchStage[T any] struct {
+	name    string
+	size    int
+	maxWait time.Duration
+}
+
+// NewBatchStage creates a batching stage. A maxWait of zero disables the
+// time window, so batches are only cut by size.
+func NewBatchStage[T any](name string, size int, maxWait time.Duration) *BatchStage[T] {
+	return &BatchStage[T]{name: name, size: max(size, 1), maxWait: maxWait}
+}
+
+func (b *BatchStage[T]) Name() string { return b.name }
+
+func (b *BatchStage[T]) Run(ctx context.Context, in <-chan T) (<-chan []T, <-chan error) {
+	out := make(chan []T, 1)
+	errCh := make(chan error)
+
+	go func() {
+		defer close(out)
+		defer close(errCh)
+		defer recoverTo(ctx, b.name, errCh)
+
+		batch := make([]T, 0, b.size)
+		timer := time.NewTimer(b.maxWait)
+		timer.Stop()
+		var window <-chan time.Time
+
+		flush := func() bool {
+			window = nil
+			timer.Stop()
+			if len(batch) == 0 {
+				return true
+			}
+			select {
+			case out <- batch:
+			case <-ctx.Done():
+				return false
+			}
+			batch = make([]T, 0, b.size)
+			return true
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				if len(batch) > 0 {
+					select {
+					case out <- batch:
+					default:
+					}
+				}
+				return
+			case m, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+				batch = append(batch, m)
+				if len(batch) == 1 && b.maxWait > 0 {
+					timer.Reset(b.maxWait)
+					window = timer.C
+				}
+				if len(batch) >= b.size && !flush() {
+					return
+				}
+			case <-window:
+				if !flush() {
+					return
+				}
+			}
+		}
+	}()
+	return out, errCh
+}
+
+// UnbatchStage flattens slices back into single items.
+type UnbatchStage[T any] struct {
+	name string
+}
+
+// NewUnbatchStage creates the counterpart of BatchStage.
+func NewUnbatchStage[T any](name string) *UnbatchStage[T] {
+	return &UnbatchStage[T]{name: name}
+}
+
+func (u *UnbatchStage[T]) Name() string { return u.name }
+
+func (u *UnbatchStage[T]) Run(ctx context.Context, in <-chan []T) (<-chan T, <-chan error) {
+	out := make(chan T)
+	errCh := make(chan error)
+
+	go func() {
+		defer close(out)
+		defer close(errCh)
+		defer recoverTo(ctx, u.name, errCh)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case batch, ok := <-in:
+				if !ok {
+					return
+				}
+				for _, m := range batch {
+					select {
+					case out <- m:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+	return out, errCh
+}
+
+var (
+	_ ports.Transformer[any, []any] = (*BatchStage[any])(nil)
+	_ ports.Transformer[[]any, any] = (*UnbatchStage[any])(nil)
+)
@@ -0,0 +1,71 @@
+package pipelines_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-pipeline/internal/pipelines"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchStageSizeAndFlushOnClose(t *testing.T) {
+	batch := pipelines.NewBatchStage[int]("batch", 2, 0)
+
+	batches, errs := collect(batch.Run(context.Background(), feed(1, 2, 3, 4, 5)))
+
+	assert.Empty(t, errs)
+	assert.Equal(t, [][]int{{1, 2}, {3, 4}, {5}}, batches)
+}
+
+func TestBatchStageMaxWait(t *testing.T) {
+	in := make(chan int)
+	out, _ := pipelines.NewBatchStage[int]("batch", 10, 20*time.Millisecond).
+		Run(context.Background(), in)
+
+	in <- 1
+	in <- 2
+	select {
+	case b := <-out:
+		assert.Equal(t, []int{1, 2}, b)
+	case <-time.After(time.Second):
+		t.Fatal("partial batch was not flushed after max wait")
+	}
+
+	in <- 3
+	close(in)
+	assert.Equal(t, []int{3}, <-out)
+	_, ok := <-out
+	assert.False(t, ok)
+}
+
+func TestBatchStageFlushOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	out, _ := pipelines.NewBatchStage[int]("batch", 10, 0).Run(ctx, in)
+
+	in <- 1
+	in <- 2
+	cancel()
+
+	var got [][]int
+	for b := range out {
+		got = append(got, b)
+	}
+	require.Len(t, got, 1)
+	assert.Equal(t, []int{1, 2}, got[0])
+}
+
+func TestUnbatchStage(t *testing.T) {
+	flow := pipelines.Then(
+		pipelines.From[int, []int](pipelines.NewBatchStage[int]("batch", 3, 0)),
+		pipelines.NewUnbatchStage[int]("unbatch"),
+	)
+
+	items, errs := collect(flow.Run(context.Background(), feed(1, 2, 3, 4, 5)))
+
+	assert.Empty(t, errs)
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, items)
+}
@@ -0,0 +1,43 @@
+package pipelines
+
+import (
+	"fmt"
+
+	"go-pipeline/pkg/apperror"
+)
+
+// withStage returns err as an *apperror.StageError[T]. Errors that already
+// are stage errors keep their stage and item; only a higher attempt count is
+// recorded on a copy.
+func withStage[T any](stage string, item T, attempt int, err error) error {
+	if se, ok := apperror.AsStageError[T](err); ok {
+		if attempt <= se.Attempt {
+			return err
+		}
+		cp := *se
+		cp.Attempt = attempt
+		return &cp
+	}
+	return apperror.NewStageError(stage, item, attempt, err)
+}
+
+// tagErrors forwards a stage's errors and names the stage in the ones the
+// stage did not type itself. The failing item is unknown for those, so they
+// stay plain errors rather than a StageError[T] with a zero item, and are
+// not dead-lettered.
+func tagErrors[T any](stage string, errCh <-chan error) <-chan error {
+	out := make(chan error, cap(errCh))
+	go func() {
+		defer close(out)
+		for err := range errCh {
+			switch _, ok := apperror.AsStageError[T](err); {
+			case err == nil:
+			case ok:
+				out <- err
+			default:
+				out <- fmt.Errorf("stage %s: %w", stage, err)
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,153 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+)
+
+// ErrDropped is returned by a function made with ToFn when the stage passed
+// no item on and reported no error, e.g. because it filtered the item out.
+// Item stages return it to drop an item, which RunItems does without
+// reporting an error. A short-circuit run ends on it without an error and
+// without compensating or dead-lettering anything.
+var ErrDropped = errors.New("item dropped by stage")
+
+// Map turns a per-item function into a stage. Failed items are reported as
+// *apperror.StageError[T] and are not emitted. The stage is a
+// ports.ItemStage, so runners recover its panics item by item.
+func Map[T any](name string, fn func(ctx context.Context, m T) (T, error)) ports.ItemStage[T] {
+	return &mapStage[T]{name: name, fn: fn}
+}
+
+// Tap calls fn for every item and passes the item on unchanged, e.g. to log
+// or count what goes through.
+func Tap[T any](name string, fn func(ctx context.Context, m T)) ports.ItemStage[T] {
+	return Map(name, func(ctx context.Context, m T) (T, error) {
+		fn(ctx, m)
+		return m, nil
+	})
+}
+
+// FlatMap turns a one-to-many function into a stage: every item is replaced
+// by the items fn returns, in order, and none at all when fn fails. A panic
+// in fn fails the item with a *PanicError.
+func FlatMap[T any](name string, fn func(ctx context.Context, m T) ([]T, error)) ports.Stage[T] {
+	return &flatMapStage[T]{name: name, fn: fn}
+}
+
+// Filter passes on the items keep accepts and drops the others silently.
+func Filter[T any](name string, keep func(T) bool) ports.Stage[T] {
+	return FlatMap(name, func(_ context.Context, m T) ([]T, error) {
+		if !keep(m) {
+			return nil, nil
+		}
+		return []T{m}, nil
+	})
+}
+
+// FromFn runs a function stage in the channel runners. It is Map for a
+// ports.StageFn.
+func FromFn[T any](name string, fn ports.StageFn[T]) ports.ItemStage[T] {
+	return Map(name, fn)
+}
+
+// ToFn calls a channel stage for one item at a time, so it can run in a
+// RunnerShortCircuit. Item stages are called through Process; any other
+// stage gets the item on its own input channel and has to pass on exactly
+// one item or fail it. Worker stages run without their workers, which have
+// nothing to share for a single item.
+func ToFn[T any](stage ports.Stage[T]) ports.StageFn[T] {
+	if w, ok := stage.(*WorkerStage[T]); ok {
+		stage = w.stage
+	}
+	if is, ok := stage.(ports.ItemStage[T]); ok {
+		return is.Process
+	}
+	return func(ctx context.Context, m T) (T, error) {
+		items, errs := invokeStage(ctx, stage, m)
+		switch {
+		case len(errs) > 0:
+			return m, errors.Join(errs...)
+		case ctx.Err() != nil:
+			return m, ctx.Err()
+		case len(items) == 0:
+			return m, ErrDropped
+		case len(items) > 1:
+			return m, fmt.Errorf("%w: stage %s passed on %d items for one",
+				apperror.ErrInternal, stage.Name(), len(items))
+		}
+		return items[0], nil
+	}
+}
+
+type mapStage[T any] struct {
+	name string
+	fn   func(ctx context.Context, m T) (T, error)
+}
+
+func (s *mapStage[T]) Name() string { return s.name }
+
+func (s *mapStage[T]) Run(ctx context.Context, in <-chan T) (<-chan T, <-chan error) {
+	return RunItems[T](ctx, s, in)
+}
+
+func (s *mapStage[T]) Process(ctx context.Context, m T) (T, error) { return s.fn(ctx, m) }
+
+type flatMapStage[T any] struct {
+	name string
+	fn   func(ctx context.Context, m T) ([]T, error)
+}
+
+func (s *flatMapStage[T]) Name() string { return s.name }
+
+func (s *flatMapStage[T]) Run(ctx context.Context, in <-chan T) (<-chan T, <-chan error) {
+	buf := BuffersFrom(ctx)
+	out := make(chan T, buf.Data)
+	errCh := make(chan error, buf.Err)
+
+	go func() {
+		defer close(out)
+		defer close(errCh)
+		defer recoverTo(ctx, s.name, errCh)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-in:
+				if !ok {
+					return
+				}
+				if !s.process(ctx, m, out, errCh) {
+					return
+				}
+			}
+		}
+	}()
+	return out, errCh
+}
+
+// process runs fn for one item and emits its results or its error. It
+// returns false when ctx is done.
+func (s *flatMapStage[T]) process(ctx context.Context, m T, out chan<- T, errCh chan<- error) bool {
+	items, err := recovered(s.name, nil, func() ([]T, error) {
+		return s.fn(ctx, m)
+	})
+	if err != nil {
+		return trySend(ctx, errCh, withStage(s.name, m, 1, err))
+	}
+	for _, v := range items {
+		if !trySend(ctx, out, v) {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	_ ports.ItemStage[any] = (*mapStage[any])(nil)
+	_ ports.Stage[any]     = (*flatMapStage[any])(nil)
+)
@@ -0,0 +1,163 @@
+package pipelines_test
+
+import (
+	"context"
+	"testing"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuncStages(t *testing.T) {
+	tests := []struct {
+		name     string
+		stage    ports.Stage[int]
+		want     []int
+		wantErrs []int // items that fail
+		panics   bool
+	}{
+		{
+			name: "Map",
+			stage: pipelines.Map("double", func(_ context.Context, m int) (int, error) {
+				if m == 3 {
+					return m, apperror.ErrInvalidInput
+				}
+				return m * 2, nil
+			}),
+			want:     []int{2, 4, 8},
+			wantErrs: []int{3},
+		},
+		{
+			name: "MapPanic",
+			stage: pipelines.Map("double", func(_ context.Context, m int) (int, error) {
+				if m == 2 {
+					panic("boom")
+				}
+				return m * 2, nil
+			}),
+			want:     []int{2, 6, 8},
+			wantErrs: []int{2},
+			panics:   true,
+		},
+		{
+			name:  "Filter",
+			stage: pipelines.Filter("even", func(m int) bool { return m%2 == 0 }),
+			want:  []int{2, 4},
+		},
+		{
+			name: "FlatMap",
+			stage: pipelines.FlatMap("repeat", func(_ context.Context, m int) ([]int, error) {
+				if m == 4 {
+					return []int{m}, apperror.ErrInvalidInput
+				}
+				return []int{m, m}, nil
+			}),
+			want:     []int{1, 1, 2, 2, 3, 3},
+			wantErrs: []int{4},
+		},
+		{
+			name: "FlatMapPanic",
+			stage: pipelines.FlatMap("repeat", func(_ context.Context, m int) ([]int, error) {
+				if m == 1 {
+					panic("boom")
+				}
+				return []int{m}, nil
+			}),
+			want:     []int{2, 3, 4},
+			wantErrs: []int{1},
+			panics:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, errs := collect(tt.stage.Run(context.Background(), feed(1, 2, 3, 4)))
+
+			assert.Equal(t, tt.want, items)
+			require.Len(t, errs, len(tt.wantErrs))
+			for i, err := range errs {
+				se, ok := apperror.AsStageError[int](err)
+				require.True(t, ok)
+				assert.Equal(t, tt.stage.Name(), se.Stage)
+				assert.Equal(t, tt.wantErrs[i], se.Item)
+				if tt.panics {
+					requirePanic(t, err)
+				}
+			}
+		})
+	}
+}
+
+func TestTap(t *testing.T) {
+	var seen []int
+	stage := pipelines.Tap("seen", func(_ context.Context, m int) { seen = append(seen, m) })
+
+	items, errs := collect(stage.Run(context.Background(), feed(1, 2, 3)))
+
+	assert.Equal(t, []int{1, 2, 3}, items)
+	assert.Empty(t, errs)
+	assert.Equal(t, []int{1, 2, 3}, seen)
+}
+
+func TestFuncStagesCancel(t *testing.T) {
+	stages := []ports.Stage[int]{
+		pipelines.Map("map", func(_ context.Context, m int) (int, error) { return m, nil }),
+		pipelines.Filter("filter", func(int) bool { return true }),
+	}
+	for _, stage := range stages {
+		t.Run(stage.Name(), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			in := make(chan int) // never closed
+			out, errCh := stage.Run(ctx, in)
+			cancel()
+
+			// collect returns only once both channels are closed
+			items, errs := collect(out, errCh)
+			assert.Empty(t, items)
+			assert.Empty(t, errs)
+		})
+	}
+}
+
+func TestToFn(t *testing.T) {
+	double := pipelines.Map("double", func(_ context.Context, m int) (int, error) {
+		return m * 2, nil
+	})
+
+	tests := []struct {
+		name    string
+		stage   ports.Stage[int]
+		want    int
+		wantErr error
+		letters int // dead letters
+	}{
+		{"ItemStage", double, 4, nil, 0},
+		{"Workers", pipelines.NewWorkerStage[int](double, 4), 4, nil, 0},
+		{"ChannelStage", &rejectStage{name: "even", reject: rejectOdd}, 2, nil, 0},
+		{"Fails", &rejectStage{name: "odd", reject: func(int) error {
+			return apperror.ErrInvalidInput
+		}}, 2, apperror.ErrInvalidInput, 1},
+		// a drop ends the run without an error
+		{"Dropped", pipelines.Filter("none", func(int) bool { return false }), 2, nil, 0},
+		{"Many", pipelines.FlatMap("twice", func(_ context.Context, m int) ([]int, error) {
+			return []int{m, m}, nil
+		}), 2, apperror.ErrInternal, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := pipelines.NewMemoryDeadLetterSink[int]()
+			r := pipelines.NewRunnerShortCircuit(pipelines.ToFn(tt.stage)).WithDeadLetter(sink)
+
+			out, err := r.Run(context.Background(), 2)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, out)
+			assert.Len(t, sink.Letters(), tt.letters)
+		})
+	}
+}
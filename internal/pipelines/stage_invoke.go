@@ -0,0 +1,84 @@
+package pipelines
+
+import (
+	"context"
+
+	"go-pipeline/internal/ports"
+)
+
+// invokeStage runs a channel stage for a single item and waits for it to
+// finish. It returns everything the stage emitted for that item, which lets
+// decorators (retry, timeout, ...) treat any ports.Stage as a per-item call.
+// Item stages run through RunItems, like in the runners, so their panics
+// fail the item.
+func invokeStage[T any](ctx context.Context, stage ports.Stage[T], item T) ([]T, []error) {
+	in := make(chan T, 1)
+	in <- item
+	close(in)
+
+	out, errCh := isolate(stage).Run(ctx, in)
+	var items []T
+	var errs []error
+	for out != nil || errCh != nil {
+		select {
+		case m, ok := <-out:
+			if !ok {
+				out = nil
+				continue
+			}
+			items = append(items, m)
+		case e, ok := <-errCh:
+			if !ok {
+				errCh = nil
+				continue
+			}
+			if e != nil {
+				errs = append(errs, e)
+			}
+		}
+	}
+	return items, errs
+}
+
+// runEach runs a decorator that handles every item of in on its own, like
+// the retry, timeout and circuit breaker stages. process returns the outputs
+// and the errors of one item; the errors are emitted first. The channels are
+// buffered as BuffersFrom says, and a panic ends the stage as with recoverTo.
+func runEach[T any](
+	ctx context.Context,
+	name string,
+	in <-chan T,
+	process func(ctx context.Context, m T) ([]T, []error),
+) (<-chan T, <-chan error) {
+	buf := BuffersFrom(ctx)
+	out := make(chan T, buf.Data)
+	errCh := make(chan error, buf.Err)
+
+	go func() {
+		defer close(out)
+		defer close(errCh)
+		defer recoverTo(ctx, name, errCh)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-in:
+				if !ok {
+					return
+				}
+				items, errs := process(ctx, m)
+				for _, e := range errs {
+					if !trySend(ctx, errCh, e) {
+						return
+					}
+				}
+				for _, v := range items {
+					if !trySend(ctx, out, v) {
+						return
+					}
+				}
+			}
+		}
+	}()
+	return out, errCh
+}
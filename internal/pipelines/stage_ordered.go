@@ -0,0 +1,160 @@
+package pipelines
+
+import (
+	"context"
+	"sync"
+
+	"go-pipeline/internal/ports"
+)
+
+// OrderedStage processes items concurrently with a StageFn but emits the
+// results in the same order the items arrived on `in`.
+//
+// Each item is tagged with a sequence number on entry and handed to one of
+// the workers. Finished results are parked in a reorder buffer until every
+// earlier item has been emitted. At most `window` items can be in flight or
+// waiting in that buffer, so a single slow item applies backpressure instead
+// of letting memory grow without limit. Errors are emitted in order as well,
+// as *apperror.StageError[T] holding the failed input.
+type OrderedStage[T any] struct {
+	name    string
+	fn      ports.StageFn[T]
+	workers int
+	window  int
+}
+
+// NewOrderedStage creates an order-preserving concurrent stage. The window is
+// a hard limit: the workers are cut down to it, since a worker beyond the
+// window would never have an item to work on.
+//
+// The built-in pipelines do not use it, none of their stages depends on the
+// order of the items. It is meant to wrap the StageFn of a stage that does,
+// which the flat stage list of the registry cannot express.
+func NewOrderedStage[T any](
+	name string,
+	fn ports.StageFn[T],
+	workers, window int,
+) *OrderedStage[T] {
+	window = max(window, 1)
+	return &OrderedStage[T]{
+		name:    name,
+		fn:      fn,
+		workers: min(max(workers, 1), window),
+		window:  window,
+	}
+}
+
+func (o *OrderedStage[T]) Name() string { return o.name }
+
+type seqItem[T any] struct {
+	seq uint64
+	in  T // the item as it arrived, kept for the error
+	val T
+	err error
+}
+
+func (o *OrderedStage[T]) Run(ctx context.Context, in <-chan T) (<-chan T, <-chan error) {
+	out := make(chan T, o.window)
+	errCh := make(chan error, o.window)
+
+	// slots bounds the number of sequence numbers between entry and emission.
+	slots := make(chan struct{}, o.window)
+	jobs := make(chan seqItem[T])
+	results := make(chan seqItem[T], o.window)
+
+	go o.dispatch(ctx, in, slots, jobs)
+
+	var wg sync.WaitGroup
+	wg.Add(o.workers)
+	for range o.workers {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				j.val, j.err = recovered(o.name, j.in, func() (T, error) {
+					return o.fn(ctx, j.in)
+				})
+				results <- j
+			}
+		}()
+	}
+	go func() { wg.Wait(); close(results) }()
+
+	go func() {
+		defer close(out)
+		defer close(errCh)
+		defer recoverTo(ctx, o.name, errCh)
+		o.resequence(ctx, results, slots, out, errCh)
+	}()
+
+	return out, errCh
+}
+
+// dispatch tags incoming items with a sequence number and waits for a free
+// slot before handing each one to the workers.
+func (o *OrderedStage[T]) dispatch(
+	ctx context.Context,
+	in <-chan T,
+	slots chan<- struct{},
+	jobs chan<- seqItem[T],
+) {
+	defer close(jobs)
+	var seq uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m, ok := <-in:
+			if !ok {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case slots <- struct{}{}:
+			}
+			jobs <- seqItem[T]{seq: seq, in: m}
+			seq++
+		}
+	}
+}
+
+// resequence parks out-of-order results and emits them once every earlier
+// sequence number has been emitted, releasing one slot per emitted result.
+func (o *OrderedStage[T]) resequence(
+	ctx context.Context,
+	results <-chan seqItem[T],
+	slots <-chan struct{},
+	out chan<- T,
+	errCh chan<- error,
+) {
+	pending := make(map[uint64]seqItem[T], o.window)
+	var next uint64
+	for r := range results {
+		pending[r.seq] = r
+		for {
+			p, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			<-slots
+
+			if p.err != nil {
+				select {
+				case errCh <- withStage(o.name, p.in, 1, p.err):
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+			select {
+			case out <- p.val:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+var _ ports.Stage[any] = (*OrderedStage[any])(nil)
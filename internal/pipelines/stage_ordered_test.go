@@ -0,0 +1,83 @@
+package pipelines_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderedStage(t *testing.T) {
+	errOdd := errors.New("odd")
+
+	tests := []struct {
+		name     string
+		workers  int
+		window   int
+		wantPeak int // most items in fn at once
+	}{
+		{"Sequential", 1, 1, 1},
+		{"WideWindow", 4, 8, 4},
+		{"NarrowWindow", 4, 2, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inFlight, peak atomic.Int32
+			fn := func(ctx context.Context, m int) (int, error) {
+				n := inFlight.Add(1)
+				defer inFlight.Add(-1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				// later items finish first to force reordering
+				time.Sleep(time.Duration(10-m) * time.Millisecond)
+				if m == 3 {
+					return m, errOdd
+				}
+				return m * 10, nil
+			}
+			stage := pipelines.NewOrderedStage("ordered", fn, tt.workers, tt.window)
+			out, errCh := stage.Run(context.Background(), feed(0, 1, 2, 3, 4, 5, 6, 7, 8, 9))
+			items, errs := collect(out, errCh)
+
+			assert.Equal(t, []int{0, 10, 20, 40, 50, 60, 70, 80, 90}, items)
+			require.Len(t, errs, 1)
+			assert.ErrorIs(t, errs[0], errOdd)
+			se, ok := apperror.AsStageError[int](errs[0])
+			require.True(t, ok)
+			assert.Equal(t, "ordered", se.Stage)
+			assert.Equal(t, 3, se.Item)
+			assert.LessOrEqual(t, int(peak.Load()), tt.wantPeak)
+		})
+	}
+}
+
+func TestOrderedStagePanic(t *testing.T) {
+	fn := func(_ context.Context, m int) (int, error) {
+		if m == 1 {
+			panic("boom")
+		}
+		return m, nil
+	}
+	stage := pipelines.NewOrderedStage("ordered", fn, 2, 2)
+
+	items, errs := collect(stage.Run(context.Background(), feed(0, 1, 2)))
+
+	assert.Equal(t, []int{0, 2}, items)
+	require.Len(t, errs, 1)
+	requirePanic(t, errs[0])
+	se, ok := apperror.AsStageError[int](errs[0])
+	require.True(t, ok)
+	assert.Equal(t, 1, se.Item)
+}
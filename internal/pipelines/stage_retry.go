@@ -0,0 +1,152 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+)
+
+// RetryPolicy describes how a failed item is retried.
+//
+// Delays grow exponentially from BaseDelay by Multiplier and are capped at
+// MaxDelay. Jitter spreads each delay randomly by up to that fraction in
+// either direction, so workers that failed together do not retry together.
+// Only errors accepted by Retryable are retried; everything else fails on
+// the first attempt.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first one
+	BaseDelay   time.Duration // delay before the first retry
+	MaxDelay    time.Duration // upper bound for a single delay, none when zero
+	Multiplier  float64       // growth factor between delays, 2 when zero
+	Jitter      float64       // random spread in [0, 1]
+
+	// Retryable classifies errors, IsRetryable is used when nil.
+	Retryable func(err error) bool
+	// OnRetry is called before every retry, e.g. to log the attempt.
+	OnRetry func(ctx context.Context, stage string, attempt int, err error, delay time.Duration)
+}
+
+// IsRetryable reports whether err is a transient failure worth retrying:
+// an unavailable dependency or a timeout. Calls rejected by an open circuit
+// breaker are not retried, the circuit stays open for its whole cooldown.
+func IsRetryable(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	return errors.Is(err, apperror.ErrUnavailable) || errors.Is(err, apperror.ErrTimeout)
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, the attempts
+// are exhausted or ctx is done. It returns the number of attempts made and
+// the last error.
+func (p RetryPolicy) Do(
+	ctx context.Context,
+	stage string,
+	fn func(ctx context.Context) error,
+) (int, error) {
+	maxAttempts := max(p.MaxAttempts, 1)
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(ctx); err == nil {
+			return attempt, nil
+		}
+		if attempt >= maxAttempts || !retryable(err) {
+			return attempt, err
+		}
+
+		delay := p.Backoff(attempt)
+		if p.OnRetry != nil {
+			p.OnRetry(ctx, stage, attempt, err, delay)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt, err
+		case <-timer.C:
+		}
+	}
+}
+
+// Backoff returns the delay to wait after the given (1-based) failed attempt.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	mult := p.Multiplier
+	if mult <= 0 {
+		mult = 2
+	}
+	delay := float64(p.BaseDelay) * math.Pow(mult, float64(attempt-1))
+	if p.MaxDelay > 0 {
+		delay = math.Min(delay, float64(p.MaxDelay))
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(max(delay, 0))
+}
+
+// RetryFn wraps a StageFn so that retryable failures are retried according
+// to the policy. The final failure is reported as an *apperror.StageError
+// carrying the number of attempts.
+func RetryFn[T any](name string, fn ports.StageFn[T], policy RetryPolicy) ports.StageFn[T] {
+	return func(ctx context.Context, m T) (T, error) {
+		var res T
+		attempts, err := policy.Do(ctx, name, func(ctx context.Context) error {
+			var errFn error
+			res, errFn = fn(ctx, m)
+			return errFn
+		})
+		if err != nil {
+			return res, withStage(name, m, attempts, err)
+		}
+		return res, nil
+	}
+}
+
+// RetryStage wraps a channel stage and retries every item that fails with a
+// retryable error. Each item is run through the wrapped stage on its own, and
+// its outputs are only emitted once an attempt succeeds.
+type RetryStage[T any] struct {
+	stage  ports.Stage[T]
+	policy RetryPolicy
+}
+
+// NewRetryStage creates a retrying decorator around stage.
+func NewRetryStage[T any](stage ports.Stage[T], policy RetryPolicy) *RetryStage[T] {
+	return &RetryStage[T]{stage: stage, policy: policy}
+}
+
+func (r *RetryStage[T]) Name() string { return r.stage.Name() }
+
+func (r *RetryStage[T]) Run(ctx context.Context, in <-chan T) (<-chan T, <-chan error) {
+	return runEach(ctx, r.Name(), in, r.process)
+}
+
+// process retries a single item and returns the outputs of its last attempt,
+// or its errors if that attempt failed.
+func (r *RetryStage[T]) process(ctx context.Context, m T) ([]T, []error) {
+	var items []T
+	var errs []error
+	attempts, _ := r.policy.Do(ctx, r.Name(), func(ctx context.Context) error {
+		items, errs = invokeStage(ctx, r.stage, m)
+		return errors.Join(errs...)
+	})
+	if len(errs) == 0 {
+		return items, nil
+	}
+	for i, e := range errs {
+		errs[i] = withStage(r.Name(), m, attempts, e)
+	}
+	return nil, errs
+}
+
+var _ ports.Stage[any] = (*RetryStage[any])(nil)
@@ -0,0 +1,73 @@
+package pipelines_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryFn(t *testing.T) {
+	policy := pipelines.RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		Jitter:      0.5,
+	}
+
+	tests := []struct {
+		name         string
+		failures     int
+		err          error
+		wantAttempts int
+		wantErr      error
+	}{
+		{"Success", 0, nil, 1, nil},
+		{"RecoversAfterRetry", 2, apperror.ErrUnavailable, 3, nil},
+		{"ExhaustsAttempts", 5, apperror.ErrTimeout, 3, apperror.ErrTimeout},
+		{"NotRetryable", 5, apperror.ErrInvalidInput, 1, apperror.ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			var retried []int
+			p := policy
+			p.OnRetry = func(_ context.Context, name string, n int, _ error, _ time.Duration) {
+				assert.Equal(t, "flaky", name)
+				retried = append(retried, n)
+			}
+			fn := pipelines.RetryFn("flaky", func(ctx context.Context, m int) (int, error) {
+				attempts++
+				if attempts <= tt.failures {
+					return m, fmt.Errorf("%w: attempt %d", tt.err, attempts)
+				}
+				return m + 1, nil
+			}, p)
+
+			res, err := fn(context.Background(), 1)
+
+			assert.Equal(t, tt.wantAttempts, attempts)
+			assert.Len(t, retried, tt.wantAttempts-1)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, 2, res)
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := pipelines.RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	assert.Equal(t, 10*time.Millisecond, p.Backoff(1))
+	assert.Equal(t, 20*time.Millisecond, p.Backoff(2))
+	assert.Equal(t, 40*time.Millisecond, p.Backoff(3))
+	assert.Equal(t, 50*time.Millisecond, p.Backoff(4))
+}
@@ -0,0 +1,198 @@
+package pipelines
+
+import (
+	"context"
+	"fmt"
+
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+)
+
+// DefaultRoute is the branch name of a router's fallback pipeline.
+const DefaultRoute = "default"
+
+// routerBuffer is the capacity of every branch's input channel, so one busy
+// branch does not immediately hold up items bound for the others.
+const routerBuffer = 16
+
+// Route sends the items accepted by Match to Pipeline.
+type Route[T any] struct {
+	Name     string
+	Match    func(T) bool
+	Pipeline ports.ChainPipeline[T]
+}
+
+// Branch builds a sub-pipeline from a list of stages. A branch without
+// stages passes its items through unchanged.
+func Branch[T any](stages ...ports.Stage[T]) ports.ChainPipeline[T] {
+	return NewRunner(stages...)
+}
+
+// RouterStage sends every item to exactly one of several sub-pipelines.
+// Predicate routers pick the first route whose Match accepts the item, key
+// routers pick the branch registered under the item's key. Items that no
+// route accepts go to the fallback pipeline, or fail with
+// apperror.ErrInvalidInput if there is none.
+//
+// Used as a ports.Stage, the router merges the outputs of all branches back
+// into one stream, by default in arrival order. Branches returns the branch
+// outputs separately instead.
+type RouterStage[T any] struct {
+	name     string
+	routes   []Route[T]
+	pick     func(T) (int, bool)
+	fallback ports.ChainPipeline[T]
+	merge    func(ctx context.Context, outs map[string]<-chan T) <-chan T
+}
+
+// NewRouterStage creates a predicate router. Routes are tried in order.
+func NewRouterStage[T any](name string, routes ...Route[T]) *RouterStage[T] {
+	r := &RouterStage[T]{name: name, routes: nameRoutes(routes)}
+	r.pick = func(m T) (int, bool) {
+		for i, rt := range r.routes {
+			if rt.Match(m) {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+	return r
+}
+
+// NewKeyRouterStage creates a router that sends each item to the branch
+// registered under key(item).
+func NewKeyRouterStage[T any](
+	name string,
+	key func(T) string,
+	branches map[string]ports.ChainPipeline[T],
+) *RouterStage[T] {
+	routes := make([]Route[T], 0, len(branches))
+	index := make(map[string]int, len(branches))
+	for k, p := range branches {
+		index[k] = len(routes)
+		routes = append(routes, Route[T]{Name: k, Pipeline: p})
+	}
+	r := &RouterStage[T]{name: name, routes: routes}
+	r.pick = func(m T) (int, bool) {
+		i, ok := index[key(m)]
+		return i, ok
+	}
+	return r
+}
+
+// WithFallback sets the pipeline for items that no route accepts.
+func (r *RouterStage[T]) WithFallback(p ports.ChainPipeline[T]) *RouterStage[T] {
+	r.fallback = p
+	return r
+}
+
+// WithMerge replaces the default fan-in used by Run to combine the branch
+// outputs. The merge function must close its result once all inputs are
+// closed.
+func (r *RouterStage[T]) WithMerge(
+	merge func(ctx context.Context, outs map[string]<-chan T) <-chan T,
+) *RouterStage[T] {
+	r.merge = merge
+	return r
+}
+
+func (r *RouterStage[T]) Name() string { return r.name }
+
+func (r *RouterStage[T]) Run(ctx context.Context, in <-chan T) (<-chan T, <-chan error) {
+	outs, errs := r.Branches(ctx, in)
+	if r.merge != nil {
+		return r.merge(ctx, outs), errs
+	}
+	chs := make([]<-chan T, 0, len(outs))
+	for _, o := range outs {
+		chs = append(chs, o)
+	}
+	return fanIn(ctx, chs...), errs
+}
+
+// Branches routes the input and returns the output of every branch keyed by
+// route name (DefaultRoute for the fallback) together with the merged errors
+// of the router and all branches.
+func (r *RouterStage[T]) Branches(
+	ctx context.Context,
+	in <-chan T,
+) (map[string]<-chan T, <-chan error) {
+	inputs := make([]chan T, 0, len(r.routes)+1)
+	outs := make(map[string]<-chan T, len(r.routes)+1)
+	errs := make([]<-chan error, 0, len(r.routes)+2)
+
+	start := func(name string, p ports.ChainPipeline[T]) {
+		ch := make(chan T, routerBuffer)
+		o, e := p.Chain(ctx, ch)
+		inputs = append(inputs, ch)
+		outs[name] = o
+		errs = append(errs, e)
+	}
+	for _, rt := range r.routes {
+		start(rt.Name, rt.Pipeline)
+	}
+	if r.fallback != nil {
+		start(DefaultRoute, r.fallback)
+	}
+
+	routeErr := make(chan error, routerBuffer)
+	errs = append(errs, routeErr)
+	go r.dispatch(ctx, in, inputs, routeErr)
+
+	return outs, mergeErrors(errs...)
+}
+
+// dispatch sends every item to its branch and closes all branch inputs once
+// the router input is drained or ctx is done.
+func (r *RouterStage[T]) dispatch(
+	ctx context.Context,
+	in <-chan T,
+	inputs []chan T,
+	routeErr chan<- error,
+) {
+	defer close(routeErr)
+	defer func() {
+		for _, ch := range inputs {
+			close(ch)
+		}
+	}()
+	defer recoverTo(ctx, r.name, routeErr)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m, ok := <-in:
+			if !ok {
+				return
+			}
+			i, found := r.pick(m)
+			if !found && r.fallback == nil {
+				errR := fmt.Errorf("%w: no route for item", apperror.ErrInvalidInput)
+				if !trySend[error](ctx, routeErr, apperror.NewStageError(r.name, m, 1, errR)) {
+					return
+				}
+				continue
+			}
+			if !found {
+				i = len(r.routes)
+			}
+			select {
+			case inputs[i] <- m:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// nameRoutes names unnamed routes after their position.
+func nameRoutes[T any](routes []Route[T]) []Route[T] {
+	for i := range routes {
+		if routes[i].Name == "" {
+			routes[i].Name = fmt.Sprintf("route_%d", i)
+		}
+	}
+	return routes
+}
+
+var _ ports.Stage[any] = (*RouterStage[any])(nil)
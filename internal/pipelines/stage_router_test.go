@@ -0,0 +1,85 @@
+package pipelines_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"testing"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouterStage(t *testing.T) {
+	errSmall := errors.New("small")
+	small := &rejectStage{name: "small", reject: func(int) error { return errSmall }}
+	isSmall := func(m int) bool { return m < 10 }
+
+	tests := []struct {
+		name      string
+		router    *pipelines.RouterStage[int]
+		wantItems []int
+		wantErrs  []error
+	}{
+		{
+			name: "WithFallback",
+			router: pipelines.NewRouterStage("router", pipelines.Route[int]{
+				Match: isSmall, Pipeline: pipelines.Branch[int](small),
+			}).WithFallback(pipelines.Branch[int]()),
+			wantItems: []int{10, 20},
+			wantErrs:  []error{errSmall},
+		},
+		{
+			name: "WithoutFallback",
+			router: pipelines.NewRouterStage("router", pipelines.Route[int]{
+				Match: isSmall, Pipeline: pipelines.Branch[int](),
+			}),
+			wantItems: []int{1},
+			wantErrs:  []error{apperror.ErrInvalidInput, apperror.ErrInvalidInput},
+		},
+		{
+			name: "ByKey",
+			router: pipelines.NewKeyRouterStage("router",
+				func(m int) string { return strconv.Itoa(len(strconv.Itoa(m))) },
+				map[string]ports.ChainPipeline[int]{
+					"1": pipelines.Branch[int](small),
+					"2": pipelines.Branch[int](),
+				},
+			),
+			wantItems: []int{10, 20},
+			wantErrs:  []error{errSmall},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, errs := collect(tt.router.Run(context.Background(), feed(1, 10, 20)))
+			sort.Ints(items)
+
+			assert.Equal(t, tt.wantItems, items)
+			require.Len(t, errs, len(tt.wantErrs))
+			for i, want := range tt.wantErrs {
+				assert.ErrorIs(t, errs[i], want)
+			}
+		})
+	}
+}
+
+func TestRouterStageBranches(t *testing.T) {
+	router := pipelines.NewRouterStage("router", pipelines.Route[int]{
+		Name: "small", Match: func(m int) bool { return m < 10 }, Pipeline: pipelines.Branch[int](),
+	}).WithFallback(pipelines.Branch[int]())
+
+	outs, errCh := router.Branches(context.Background(), feed(1, 10, 2))
+
+	require.Len(t, outs, 2)
+	small, _ := collect(outs["small"], errCh)
+	large, _ := collect(outs[pipelines.DefaultRoute], nil)
+	assert.Equal(t, []int{1, 2}, small)
+	assert.Equal(t, []int{10}, large)
+}
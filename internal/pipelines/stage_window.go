@@ -0,0 +1,424 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+)
+
+// ErrLateItem is reported for items whose windows were already emitted.
+var ErrLateItem = errors.New("item arrived after its window closed")
+
+type windowKind int
+
+const (
+	windowTumbling windowKind = iota
+	windowSliding
+	windowSession
+)
+
+// Window describes how a WindowStage groups items in time.
+type Window struct {
+	kind  windowKind
+	size  time.Duration
+	slide time.Duration
+	gap   time.Duration
+}
+
+// TumblingWindow groups items into fixed, non-overlapping windows of size.
+func TumblingWindow(size time.Duration) Window {
+	return Window{kind: windowTumbling, size: size, slide: size}
+}
+
+// SlidingWindow groups items into windows of size that start every slide, so
+// an item belongs to several windows when slide is smaller than size.
+func SlidingWindow(size, slide time.Duration) Window {
+	return Window{kind: windowSliding, size: size, slide: slide}
+}
+
+// SessionWindow groups the items of a key into sessions that stay open as
+// long as the next item arrives within gap of the previous one.
+func SessionWindow(gap time.Duration) Window {
+	return Window{kind: windowSession, gap: gap}
+}
+
+// validate rejects windows that would never close or, for sliding windows,
+// leave gaps between them.
+func (w Window) validate() error {
+	switch w.kind {
+	case windowSession:
+		return positive("session window gap", w.gap)
+	case windowSliding:
+		if err := positive("sliding window size", w.size); err != nil {
+			return err
+		}
+		if w.slide > w.size {
+			return fmt.Errorf("%w: sliding window slide %v exceeds its size %v",
+				apperror.ErrInvalidInput, w.slide, w.size)
+		}
+		return positive("sliding window slide", w.slide)
+	default:
+		return positive("tumbling window size", w.size)
+	}
+}
+
+func positive(what string, d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("%w: %s must be positive, got %v", apperror.ErrInvalidInput, what, d)
+	}
+	return nil
+}
+
+// Aggregator folds the items of a window into an accumulator. Merge combines
+// two accumulators; session windows use it when an out-of-order item bridges
+// two sessions, without it those sessions stay separate.
+type Aggregator[T, A any] struct {
+	Zero  func() A
+	Add   func(acc A, m T) A
+	Merge func(a, b A) A
+}
+
+// Count counts the items of a window.
+func Count[T any]() Aggregator[T, int] {
+	return Aggregator[T, int]{
+		Zero:  func() int { return 0 },
+		Add:   func(acc int, _ T) int { return acc + 1 },
+		Merge: func(a, b int) int { return a + b },
+	}
+}
+
+// WindowResult is the aggregate of one key in one window.
+type WindowResult[K comparable, A any] struct {
+	Key   K         `json:"key"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Value A         `json:"value"`
+	Count int       `json:"count"`
+}
+
+// WindowStage aggregates items per key and window and emits one
+// WindowResult per key and window once the window closes.
+//
+// By default items are placed by processing time and windows close when the
+// clock passes their end. With an event-time extractor, windows close when
+// the largest event time seen so far (the watermark) passes their end plus
+// the allowed lateness; items for windows that already closed are reported
+// as ErrLateItem. When the input closes all open windows are emitted.
+type WindowStage[T any, K comparable, A any] struct {
+	name      string
+	window    Window
+	key       func(T) K
+	agg       Aggregator[T, A]
+	eventTime func(T) time.Time
+	lateness  time.Duration
+	clock     Clock
+}
+
+// NewWindowStage creates a processing-time window stage. Windows with a
+// size, slide or gap that is not positive, and sliding windows that slide
+// further than their size, are rejected with apperror.ErrInvalidInput.
+func NewWindowStage[T any, K comparable, A any](
+	name string,
+	window Window,
+	key func(T) K,
+	agg Aggregator[T, A],
+) (*WindowStage[T, K, A], error) {
+	if err := window.validate(); err != nil {
+		return nil, fmt.Errorf("window stage %s: %w", name, err)
+	}
+	return &WindowStage[T, K, A]{
+		name:   name,
+		window: window,
+		key:    key,
+		agg:    agg,
+		clock:  SystemClock,
+	}, nil
+}
+
+// WithEventTime places items by the time returned by fn instead of the time
+// they arrive.
+func (s *WindowStage[T, K, A]) WithEventTime(fn func(T) time.Time) *WindowStage[T, K, A] {
+	s.eventTime = fn
+	return s
+}
+
+// WithAllowedLateness keeps windows open for d after their end, so items
+// arriving slightly out of order are still counted.
+func (s *WindowStage[T, K, A]) WithAllowedLateness(d time.Duration) *WindowStage[T, K, A] {
+	s.lateness = d
+	return s
+}
+
+// WithClock replaces the SystemClock.
+func (s *WindowStage[T, K, A]) WithClock(c Clock) *WindowStage[T, K, A] {
+	s.clock = c
+	return s
+}
+
+func (s *WindowStage[T, K, A]) Name() string { return s.name }
+
+func (s *WindowStage[T, K, A]) Run(
+	ctx context.Context,
+	in <-chan T,
+) (<-chan WindowResult[K, A], <-chan error) {
+	out := make(chan WindowResult[K, A])
+	errCh := make(chan error)
+
+	go func() {
+		defer close(out)
+		defer close(errCh)
+		defer recoverTo(ctx, s.name, errCh)
+
+		st := newWindowState(s)
+		var timer <-chan time.Time
+		var timerAt time.Time
+		for {
+			if at, ok := st.nextClose(); ok && s.eventTime == nil && !at.Equal(timerAt) {
+				timerAt = at
+				timer = s.clock.After(at.Sub(s.clock.Now()))
+			}
+			var results []WindowResult[K, A]
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-in:
+				if !ok {
+					emitResults(ctx, out, st.closeAll())
+					return
+				}
+				if err := st.add(m); err != nil {
+					select {
+					case errCh <- err:
+					case <-ctx.Done():
+						return
+					}
+				}
+				results = st.closeDue()
+			case now := <-timer:
+				timer, timerAt = nil, time.Time{}
+				st.advance(now)
+				results = st.closeDue()
+			}
+			if !emitResults(ctx, out, results) {
+				return
+			}
+		}
+	}()
+	return out, errCh
+}
+
+// emitResults sends results in order. It returns false when ctx is done.
+func emitResults[K comparable, A any](
+	ctx context.Context,
+	out chan<- WindowResult[K, A],
+	results []WindowResult[K, A],
+) bool {
+	for _, r := range results {
+		select {
+		case out <- r:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+type paneID[K comparable] struct {
+	key   K
+	start int64
+}
+
+// windowState holds the open windows of a single WindowStage run.
+type windowState[T any, K comparable, A any] struct {
+	stage     *WindowStage[T, K, A]
+	fixed     map[paneID[K]]*WindowResult[K, A]
+	sessions  map[K][]*WindowResult[K, A]
+	watermark time.Time
+}
+
+func newWindowState[T any, K comparable, A any](s *WindowStage[T, K, A]) *windowState[T, K, A] {
+	return &windowState[T, K, A]{
+		stage:    s,
+		fixed:    make(map[paneID[K]]*WindowResult[K, A]),
+		sessions: make(map[K][]*WindowResult[K, A]),
+	}
+}
+
+// add places m into its windows and moves the watermark forward.
+func (st *windowState[T, K, A]) add(m T) error {
+	s := st.stage
+	t := s.clock.Now()
+	if s.eventTime != nil {
+		t = s.eventTime(m)
+	}
+
+	var added bool
+	if s.window.kind == windowSession {
+		added = st.addSession(s.key(m), t, m)
+	} else {
+		added = st.addFixed(s.key(m), t, m)
+	}
+	st.advance(t)
+	if !added {
+		return withStage(s.name, m, 1,
+			fmt.Errorf("%w: event time %s", ErrLateItem, t.Format(time.RFC3339Nano)))
+	}
+	return nil
+}
+
+func (st *windowState[T, K, A]) advance(t time.Time) {
+	if t.After(st.watermark) {
+		st.watermark = t
+	}
+}
+
+// closed reports whether a window ending at end is past the watermark.
+func (st *windowState[T, K, A]) closed(end time.Time) bool {
+	return !end.Add(st.stage.lateness).After(st.watermark)
+}
+
+// addFixed adds m to every tumbling or sliding window covering t that is
+// still open. Windows start at multiples of the slide.
+func (st *windowState[T, K, A]) addFixed(key K, t time.Time, m T) bool {
+	w, agg := st.stage.window, st.stage.agg
+	var added bool
+	for start := t.Truncate(w.slide); start.Add(w.size).After(t); start = start.Add(-w.slide) {
+		end := start.Add(w.size)
+		if st.closed(end) {
+			break
+		}
+		id := paneID[K]{key: key, start: start.UnixNano()}
+		p := st.fixed[id]
+		if p == nil {
+			p = &WindowResult[K, A]{Key: key, Start: start, End: end, Value: agg.Zero()}
+			st.fixed[id] = p
+		}
+		p.Value = agg.Add(p.Value, m)
+		p.Count++
+		added = true
+	}
+	return added
+}
+
+// addSession adds m to the session of key that [t, t+gap) overlaps, merging
+// sessions it bridges, or starts a new one.
+func (st *windowState[T, K, A]) addSession(key K, t time.Time, m T) bool {
+	gap, agg := st.stage.window.gap, st.stage.agg
+	start, end := t, t.Add(gap)
+	if st.closed(end) {
+		return false
+	}
+
+	var target *WindowResult[K, A]
+	kept := make([]*WindowResult[K, A], 0, len(st.sessions[key])+1)
+	for _, p := range st.sessions[key] {
+		overlaps := p.Start.Before(end) && start.Before(p.End)
+		switch {
+		case overlaps && target == nil:
+			target = p
+		case overlaps && agg.Merge != nil:
+			target.Value = agg.Merge(target.Value, p.Value)
+			target.Count += p.Count
+			target.Start, target.End = minTime(target.Start, p.Start), maxTime(target.End, p.End)
+			continue
+		}
+		kept = append(kept, p)
+	}
+	if target == nil {
+		target = &WindowResult[K, A]{Key: key, Start: start, End: end, Value: agg.Zero()}
+		kept = append(kept, target)
+	}
+	target.Value = agg.Add(target.Value, m)
+	target.Count++
+	target.Start, target.End = minTime(target.Start, start), maxTime(target.End, end)
+	st.sessions[key] = kept
+	return true
+}
+
+// nextClose returns the time at which the earliest open window closes.
+func (st *windowState[T, K, A]) nextClose() (time.Time, bool) {
+	var next time.Time
+	found := false
+	st.each(func(p *WindowResult[K, A]) bool {
+		if !found || p.End.Before(next) {
+			next, found = p.End, true
+		}
+		return false
+	})
+	return next.Add(st.stage.lateness), found
+}
+
+// closeDue removes and returns the windows past the watermark.
+func (st *windowState[T, K, A]) closeDue() []WindowResult[K, A] {
+	return st.remove(func(p *WindowResult[K, A]) bool { return st.closed(p.End) })
+}
+
+// closeAll removes and returns all open windows.
+func (st *windowState[T, K, A]) closeAll() []WindowResult[K, A] {
+	return st.remove(func(*WindowResult[K, A]) bool { return true })
+}
+
+// remove drops the windows accepted by match and returns them ordered by end.
+func (st *windowState[T, K, A]) remove(
+	match func(p *WindowResult[K, A]) bool,
+) []WindowResult[K, A] {
+	var results []WindowResult[K, A]
+	st.each(func(p *WindowResult[K, A]) bool {
+		if match(p) {
+			results = append(results, *p)
+			return true
+		}
+		return false
+	})
+	sort.SliceStable(results, func(i, j int) bool {
+		if !results[i].End.Equal(results[j].End) {
+			return results[i].End.Before(results[j].End)
+		}
+		return results[i].Start.Before(results[j].Start)
+	})
+	return results
+}
+
+// each calls fn for every open window and drops the windows fn returns true
+// for.
+func (st *windowState[T, K, A]) each(fn func(p *WindowResult[K, A]) bool) {
+	for id, p := range st.fixed {
+		if fn(p) {
+			delete(st.fixed, id)
+		}
+	}
+	for key, sessions := range st.sessions {
+		kept := sessions[:0]
+		for _, p := range sessions {
+			if !fn(p) {
+				kept = append(kept, p)
+			}
+		}
+		if len(kept) == 0 {
+			delete(st.sessions, key)
+			continue
+		}
+		st.sessions[key] = kept
+	}
+}
+
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+var _ ports.Transformer[any, WindowResult[string, int]] = (*WindowStage[any, string, int])(nil)
@@ -0,0 +1,171 @@
+package pipelines_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type event struct {
+	key string
+	at  time.Duration
+}
+
+func eventKey(e event) string { return e.key }
+
+func eventTime(e event) time.Time { return epoch.Add(e.at) }
+
+type window struct {
+	key   string
+	start time.Duration
+	end   time.Duration
+	count int
+}
+
+func windows(results []pipelines.WindowResult[string, int]) []window {
+	out := make([]window, 0, len(results))
+	for _, r := range results {
+		out = append(out, window{
+			key:   r.Key,
+			start: r.Start.Sub(epoch),
+			end:   r.End.Sub(epoch),
+			count: r.Count,
+		})
+	}
+	return out
+}
+
+func TestWindowStageEventTime(t *testing.T) {
+	s := time.Second
+	tests := []struct {
+		name     string
+		window   pipelines.Window
+		lateness time.Duration
+		events   []event
+		want     []window
+		late     int
+	}{
+		{
+			name:   "tumbling",
+			window: pipelines.TumblingWindow(time.Minute),
+			events: []event{{"a", 0}, {"a", 10 * s}, {"b", 30 * s}, {"a", 65 * s}, {"a", 30 * s}},
+			want: []window{
+				{"a", 0, 60 * s, 2},
+				{"b", 0, 60 * s, 1},
+				{"a", 60 * s, 120 * s, 1},
+			},
+			late: 1,
+		},
+		{
+			name:   "sliding",
+			window: pipelines.SlidingWindow(time.Minute, 30*s),
+			events: []event{{"a", 40 * s}, {"a", 70 * s}},
+			want: []window{
+				{"a", 0, 60 * s, 1},
+				{"a", 30 * s, 90 * s, 2},
+				{"a", 60 * s, 120 * s, 1},
+			},
+		},
+		{
+			name:   "session",
+			window: pipelines.SessionWindow(10 * s),
+			events: []event{{"a", 0}, {"a", 5 * s}, {"a", 30 * s}},
+			want: []window{
+				{"a", 0, 15 * s, 2},
+				{"a", 30 * s, 40 * s, 1},
+			},
+		},
+		{
+			name:     "session bridged by out of order item",
+			window:   pipelines.SessionWindow(10 * s),
+			lateness: time.Minute,
+			events:   []event{{"a", 0}, {"a", 18 * s}, {"a", 9 * s}},
+			want:     []window{{"a", 0, 28 * s, 3}},
+		},
+		{
+			name:     "allowed lateness",
+			window:   pipelines.TumblingWindow(time.Minute),
+			lateness: 10 * s,
+			events:   []event{{"a", 0}, {"a", 65 * s}, {"a", 50 * s}, {"a", 71 * s}, {"a", 5 * s}},
+			want: []window{
+				{"a", 0, 60 * s, 2},
+				{"a", 60 * s, 120 * s, 2},
+			},
+			late: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stage, err := pipelines.NewWindowStage("window", tt.window, eventKey,
+				pipelines.Count[event]())
+			require.NoError(t, err)
+			stage.WithEventTime(eventTime).WithAllowedLateness(tt.lateness)
+
+			results, errs := collect(stage.Run(context.Background(), feed(tt.events...)))
+
+			assert.ElementsMatch(t, tt.want, windows(results))
+			require.Len(t, errs, tt.late)
+			for _, err := range errs {
+				assert.True(t, errors.Is(err, pipelines.ErrLateItem))
+			}
+		})
+	}
+}
+
+func TestWindowStageProcessingTime(t *testing.T) {
+	clock := pipelines.NewManualClock(epoch)
+	in := make(chan event)
+	stage, err := pipelines.NewWindowStage("window", pipelines.TumblingWindow(time.Minute),
+		eventKey, pipelines.Count[event]())
+	require.NoError(t, err)
+	out, _ := stage.WithClock(clock).Run(context.Background(), in)
+
+	in <- event{key: "a"}
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+
+	r := <-out
+	assert.Equal(t, "a", r.Key)
+	assert.Equal(t, epoch, r.Start)
+	assert.Equal(t, epoch.Add(time.Minute), r.End)
+	assert.Equal(t, 1, r.Value)
+
+	in <- event{key: "b"}
+	close(in)
+	r = <-out
+	assert.Equal(t, "b", r.Key)
+	assert.Equal(t, epoch.Add(time.Minute), r.Start)
+	_, ok := <-out
+	assert.False(t, ok)
+}
+
+func TestWindowInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		window pipelines.Window
+	}{
+		{"TumblingZero", pipelines.TumblingWindow(0)},
+		{"SlidingZeroSlide", pipelines.SlidingWindow(time.Minute, 0)},
+		{"SlidingNegativeSize", pipelines.SlidingWindow(-time.Minute, time.Second)},
+		{"SlideBeyondSize", pipelines.SlidingWindow(time.Second, time.Minute)},
+		{"SessionZeroGap", pipelines.SessionWindow(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pipelines.NewWindowStage("window", tt.window, eventKey,
+				pipelines.Count[event]())
+			assert.ErrorIs(t, err, apperror.ErrInvalidInput)
+		})
+	}
+}
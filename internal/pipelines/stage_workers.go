@@ -0,0 +1,65 @@
+package pipelines
+
+import (
+	"context"
+	"sync"
+
+	"go-pipeline/internal/ports"
+)
+
+// WorkerStage runs N copies of a stage that all read from the same input
+// channel. Every call to the wrapped stage's Run starts one worker goroutine,
+// so any ports.Stage can be scaled out without changes to its code.
+// The outputs and errors of all workers are fanned in, and both channels are
+// closed only after every worker has finished.
+type WorkerStage[T any] struct {
+	stage   ports.Stage[T]
+	workers int
+}
+
+// NewWorkerStage wraps a stage so that it runs with the given number of
+// workers. A worker count below 2 returns the stage itself.
+func NewWorkerStage[T any](stage ports.Stage[T], workers int) ports.Stage[T] {
+	if workers < 2 {
+		return stage
+	}
+	return &WorkerStage[T]{
+		stage:   stage,
+		workers: workers,
+	}
+}
+
+func (w *WorkerStage[T]) Name() string { return w.stage.Name() }
+
+func (w *WorkerStage[T]) Run(ctx context.Context, in <-chan T) (<-chan T, <-chan error) {
+	outs := make([]<-chan T, w.workers)
+	errs := make([]<-chan error, w.workers)
+	for i := range w.workers {
+		outs[i], errs[i] = w.stage.Run(ctx, in)
+	}
+	return fanIn(ctx, outs...), mergeErrors(errs...)
+}
+
+var _ ports.Stage[any] = (*WorkerStage[any])(nil)
+
+// fanIn merges several channels into one, closing the result once every
+// source is closed. After ctx is done the remaining values are drained and
+// dropped so that upstream goroutines never block on a send.
+func fanIn[T any](ctx context.Context, chs ...<-chan T) <-chan T {
+	out := make(chan T, len(chs))
+	var wg sync.WaitGroup
+	wg.Add(len(chs))
+	for _, c := range chs {
+		go func(c <-chan T) {
+			defer wg.Done()
+			for v := range c {
+				select {
+				case out <- v:
+				case <-ctx.Done():
+				}
+			}
+		}(c)
+	}
+	go func() { wg.Wait(); close(out) }()
+	return out
+}
@@ -0,0 +1,107 @@
+package pipelines_test
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/internal/ports"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowStage doubles every item after a short sleep and records the highest
+// number of items it saw in flight at the same time.
+type slowStage struct {
+	delay    time.Duration
+	inFlight atomic.Int32
+	peak     atomic.Int32
+}
+
+func (s *slowStage) Name() string { return "slow" }
+
+func (s *slowStage) Run(ctx context.Context, in <-chan int) (<-chan int, <-chan error) {
+	out := make(chan int)
+	errCh := make(chan error)
+	go func() {
+		defer close(out)
+		defer close(errCh)
+		for m := range in {
+			n := s.inFlight.Add(1)
+			for {
+				p := s.peak.Load()
+				if n <= p || s.peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(s.delay)
+			s.inFlight.Add(-1)
+			out <- m * 2
+		}
+	}()
+	return out, errCh
+}
+
+func feed[T any](items ...T) <-chan T {
+	in := make(chan T, len(items))
+	for _, v := range items {
+		in <- v
+	}
+	close(in)
+	return in
+}
+
+func collect[T any](out <-chan T, errCh <-chan error) ([]T, []error) {
+	var items []T
+	var errs []error
+	for out != nil || errCh != nil {
+		select {
+		case v, ok := <-out:
+			if !ok {
+				out = nil
+				continue
+			}
+			items = append(items, v)
+		case e, ok := <-errCh:
+			if !ok {
+				errCh = nil
+				continue
+			}
+			errs = append(errs, e)
+		}
+	}
+	return items, errs
+}
+
+func TestWorkerStage(t *testing.T) {
+	tests := []struct {
+		name     string
+		workers  int
+		wantPeak int32
+	}{
+		{"SingleWorker", 1, 1},
+		{"FourWorkers", 4, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &slowStage{delay: 20 * time.Millisecond}
+			var stage ports.Stage[int] = pipelines.NewWorkerStage[int](inner, tt.workers)
+
+			out, errCh := pipelines.NewRunner(stage).Chain(
+				context.Background(),
+				feed(1, 2, 3, 4, 5, 6, 7, 8),
+			)
+			items, errs := collect(out, errCh)
+			sort.Ints(items)
+
+			assert.Empty(t, errs)
+			assert.Equal(t, []int{2, 4, 6, 8, 10, 12, 14, 16}, items)
+			assert.Equal(t, tt.wantPeak, inner.peak.Load())
+			assert.Equal(t, "slow", stage.Name())
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+)
+
+// TimeoutFn bounds every call of fn to d. The call gets a context with that
+// deadline; if it has not returned when the deadline passes, the item fails
+// with apperror.ErrTimeout even if fn ignores its context. Such a call keeps
+// running in the background, so do not wrap a call that ignores its context
+// and has side effects, like a send that may still go through, in a retry.
+func TimeoutFn[T any](name string, fn ports.StageFn[T], d time.Duration) ports.StageFn[T] {
+	type result struct {
+		v   T
+		err error
+	}
+	return func(ctx context.Context, m T) (T, error) {
+		tctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+
+		done := make(chan result, 1)
+		go func() {
+			// a panic here would bypass the caller's recovery
+			v, err := recovered(name, m, func() (T, error) { return fn(tctx, m) })
+			done <- result{v: v, err: err}
+		}()
+
+		select {
+		case r := <-done:
+			if r.err != nil {
+				return r.v, withStage(name, m, 1, overrun(ctx, tctx, name, d, r.err))
+			}
+			return r.v, nil
+		case <-tctx.Done():
+			return m, withStage(name, m, 1, overrun(ctx, tctx, name, d, tctx.Err()))
+		}
+	}
+}
+
+// TimeoutStage wraps a channel stage and bounds the time it may spend on a
+// single item. Each item is run through the wrapped stage on its own with a
+// per-item deadline; an item that overruns fails with apperror.ErrTimeout and
+// the stage moves on to the next one.
+type TimeoutStage[T any] struct {
+	stage   ports.Stage[T]
+	timeout time.Duration
+}
+
+// NewTimeoutStage creates a timeout decorator around stage.
+func NewTimeoutStage[T any](stage ports.Stage[T], d time.Duration) *TimeoutStage[T] {
+	return &TimeoutStage[T]{stage: stage, timeout: d}
+}
+
+func (s *TimeoutStage[T]) Name() string { return s.stage.Name() }
+
+func (s *TimeoutStage[T]) Run(ctx context.Context, in <-chan T) (<-chan T, <-chan error) {
+	return runEach(ctx, s.Name(), in, s.process)
+}
+
+// process runs a single item under its deadline and returns its outputs and
+// errors.
+func (s *TimeoutStage[T]) process(ctx context.Context, m T) ([]T, []error) {
+	type result struct {
+		items []T
+		errs  []error
+	}
+	tctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	done := make(chan result, 1)
+	go func() {
+		// the wrapped stage's own Run may panic on this goroutine
+		defer func() {
+			if r := recover(); r != nil {
+				pe := &PanicError{Stage: s.Name(), Value: r, Stack: debug.Stack()}
+				done <- result{errs: []error{pe}}
+			}
+		}()
+		items, errs := invokeStage(tctx, s.stage, m)
+		done <- result{items: items, errs: errs}
+	}()
+
+	var r result
+	select {
+	case r = <-done:
+	case <-tctx.Done():
+		r.errs = []error{tctx.Err()}
+	}
+	for i, e := range r.errs {
+		r.errs[i] = withStage(s.Name(), m, 1, overrun(ctx, tctx, s.Name(), s.timeout, e))
+	}
+	return r.items, r.errs
+}
+
+// overrun turns err into apperror.ErrTimeout when it was caused by the
+// deadline of tctx rather than by its parent ctx.
+func overrun(ctx, tctx context.Context, what string, d time.Duration, err error) error {
+	if ctx.Err() == nil && tctx.Err() != nil && errors.Is(err, tctx.Err()) {
+		return fmt.Errorf("%w: %s exceeded %s", apperror.ErrTimeout, what, d)
+	}
+	return err
+}
+
+// runWithTimeout bounds a channel pipeline run to d. A run cut short by the
+// deadline reports apperror.ErrTimeout as an extra error; the deadline is
+// released once all errors have been forwarded.
+func runWithTimeout[T any](
+	ctx context.Context,
+	d time.Duration,
+	in <-chan T,
+	run func(ctx context.Context, in <-chan T) (<-chan T, <-chan error),
+) (<-chan T, <-chan error) {
+	if d <= 0 {
+		return run(ctx, in)
+	}
+	tctx, cancel := context.WithTimeout(ctx, d)
+	out, errCh := run(tctx, in)
+
+	errs := make(chan error, cap(errCh))
+	go func() {
+		defer close(errs)
+		defer cancel()
+		for err := range errCh {
+			errs <- err
+		}
+		err := overrun(ctx, tctx, "pipeline", d, tctx.Err())
+		if errors.Is(err, apperror.ErrTimeout) {
+			errs <- err
+		}
+	}()
+	return out, errs
+}
+
+var _ ports.Stage[any] = (*TimeoutStage[any])(nil)
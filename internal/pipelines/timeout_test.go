@@ -0,0 +1,140 @@
+package pipelines_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sleepStage works m milliseconds on item m, or until ctx is done.
+type sleepStage struct{}
+
+func (sleepStage) Name() string { return "sleep" }
+
+func (sleepStage) Run(ctx context.Context, in <-chan int) (<-chan int, <-chan error) {
+	out := make(chan int)
+	errCh := make(chan error)
+	go func() {
+		defer close(out)
+		defer close(errCh)
+		for m := range in {
+			select {
+			case <-time.After(time.Duration(m) * time.Millisecond):
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case out <- m:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, errCh
+}
+
+func TestTimeoutFn(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	fn := pipelines.TimeoutFn("slow", func(ctx context.Context, m int) (int, error) {
+		if m > 0 {
+			<-block // ignores ctx
+		}
+		return m, nil
+	}, 20*time.Millisecond)
+
+	got, err := fn(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, 0, got)
+
+	_, err = fn(context.Background(), 1)
+	assert.True(t, errors.Is(err, apperror.ErrTimeout))
+	se, ok := apperror.AsStageError[int](err)
+	require.True(t, ok)
+	assert.Equal(t, "slow", se.Stage)
+}
+
+func TestTimeoutStage(t *testing.T) {
+	stage := pipelines.NewTimeoutStage[int](sleepStage{}, 50*time.Millisecond)
+
+	items, errs := collect(stage.Run(context.Background(), feed(1, 500, 2)))
+
+	assert.Equal(t, []int{1, 2}, items)
+	require.Len(t, errs, 1)
+	assert.True(t, errors.Is(errs[0], apperror.ErrTimeout))
+	se, ok := apperror.AsStageError[int](errs[0])
+	require.True(t, ok)
+	assert.Equal(t, "sleep", se.Stage)
+	assert.Equal(t, 500, se.Item)
+}
+
+func TestRunnersWithTimeout(t *testing.T) {
+	d := 30 * time.Millisecond
+	tests := []struct {
+		name string
+		run  func(ctx context.Context) []error
+	}{
+		{
+			name: "chain",
+			run: func(ctx context.Context) []error {
+				r := pipelines.NewRunner[int](sleepStage{}).WithTimeout(d)
+				_, errs := collect(r.Chain(ctx, feed(1000)))
+				return errs
+			},
+		},
+		{
+			name: "barrier",
+			run: func(ctx context.Context) []error {
+				r := pipelines.NewRunnerBarrier[int](4, sleepStage{}).WithTimeout(d)
+				_, errs := collect(r.Run(ctx, make(chan int)))
+				return errs
+			},
+		},
+		{
+			name: "dag",
+			run: func(ctx context.Context) []error {
+				r, err := pipelines.NewRunnerDAG(pipelines.Node[int](sleepStage{}))
+				require.NoError(t, err)
+				_, errs := collect(r.WithTimeout(d).Run(ctx, feed(1000)))
+				return errs
+			},
+		},
+		{
+			name: "short circuit",
+			run: func(ctx context.Context) []error {
+				calls := 0
+				step := func(ctx context.Context, m int) (int, error) {
+					calls++
+					select {
+					case <-time.After(time.Second):
+						return m, nil
+					case <-ctx.Done():
+						return m, ctx.Err()
+					}
+				}
+				r := pipelines.NewRunnerShortCircuit(step, step).WithTimeout(d)
+				_, err := r.Run(ctx, 1)
+				assert.Equal(t, 1, calls)
+				return []error{err}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			errs := tt.run(context.Background())
+
+			assert.Less(t, time.Since(start), 500*time.Millisecond)
+			require.Len(t, errs, 1)
+			assert.True(t, errors.Is(errs[0], apperror.ErrTimeout), errs[0])
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package ports
+
+import "context"
+
+// DeadLetterSink receives items that failed a pipeline stage, so they can be
+// inspected or replayed later instead of being dropped.
+//
+// Runners call DeadLetter with the failed item, the error it failed with and
+// metadata such as the stage name and error code. Implementations should be
+// safe for concurrent use, since several stages may fail at the same time.
+type DeadLetterSink[T any] interface {
+	DeadLetter(ctx context.Context, item T, err error, metadata map[string]string) error
+}
@@ -0,0 +1,19 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// DedupStore remembers the keys of items that were already processed.
+//
+// MarkSeen records key for ttl and reports whether it was new. Checking and
+// recording must be a single atomic step, so that of several concurrent
+// callers with the same key exactly one sees it as new. Forget removes key,
+// so that it is new again, e.g. after the work it guarded failed; forgetting
+// an unknown key is not an error. Implementations should report an
+// unreachable backend as apperror.ErrUnavailable.
+type DedupStore interface {
+	MarkSeen(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	Forget(ctx context.Context, key string) error
+}
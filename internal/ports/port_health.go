@@ -0,0 +1,11 @@
+package ports
+
+import "context"
+
+// HealthChecker reports the health of a component, e.g. a connection or a
+// circuit breaker guarding one. Check returns nil while the component is
+// usable and an error describing the problem otherwise.
+type HealthChecker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
@@ -0,0 +1,32 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// RunEvent describes a step of a pipeline run. Counts are those of the
+// stage for stage events, of the phase for OnBarrierPhaseComplete and of
+// the whole run for OnRunStart and OnRunEnd.
+type RunEvent struct {
+	RunID   string
+	Stage   string        // empty for run events
+	Elapsed time.Duration // since the run started, the phase duration for phases
+	In      int           // items that entered so far
+	Out     int           // items that left so far
+	Errors  int           // errors reported so far
+	Err     error         // the stage error, or what ended the run early
+}
+
+// RunListener observes pipeline runs without touching stage code, e.g. for
+// progress bars or audit trails. Callbacks are called synchronously from
+// the goroutines of the run, concurrently for channel runners, and must not
+// block.
+type RunListener interface {
+	OnRunStart(ctx context.Context, ev RunEvent)
+	OnItemIn(ctx context.Context, ev RunEvent)
+	OnItemOut(ctx context.Context, ev RunEvent)
+	OnStageError(ctx context.Context, ev RunEvent)
+	OnBarrierPhaseComplete(ctx context.Context, ev RunEvent)
+	OnRunEnd(ctx context.Context, ev RunEvent)
+}
@@ -16,6 +16,13 @@ type MessageQueueProducer interface {
 	Produce(ctx context.Context, topic string, msg interface{}) error
 }
 
+// HeaderCarrier is implemented by messages that carry their own broker
+// headers. Producers add these headers to the ones they set themselves
+// (e.g. trace-id and content-type) when the message is sent.
+type HeaderCarrier interface {
+	Headers() map[string]string
+}
+
 // MessageQueueConsumer defines the contract for a message queue consumer.
 // A consumer is responsible for connecting to the broker, continuously
 // consuming messages from one or more topics, and closing the connection.
@@ -1,6 +1,9 @@
 package ports
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // ChainPipeline defines a parallel (concurrent) pipeline.
 // Each stage processes items concurrently, and all results
@@ -18,8 +21,24 @@ type ChainPipeline[T any] interface {
 // (short-circuited) and the error is returned.
 // This is useful for validation or scenarios where
 // failure should prevent further processing.
+//
+// RunTraced behaves like Run and additionally returns one
+// StageTrace per executed stage. In debug mode the traces
+// also hold snapshots of each stage's input and output.
 type ShortCircuitPipeLine[T any] interface {
 	Run(ctx context.Context, m T) (T, error)
+	RunTraced(ctx context.Context, m T, debug bool) (T, []StageTrace[T], error)
+}
+
+// StageTrace records the execution of a single stage of a
+// short-circuit run. Input and Output are shallow copies and
+// are only set in debug mode.
+type StageTrace[T any] struct {
+	Stage    string        `json:"stage"`
+	Duration time.Duration `json:"duration_ns"`
+	Input    *T            `json:"input,omitempty"`
+	Output   *T            `json:"output,omitempty"`
+	Error    string        `json:"error,omitempty"`
 }
 
 // BarrierPipeLine defines a pipeline that processes inputs
@@ -31,3 +50,22 @@ type ShortCircuitPipeLine[T any] interface {
 type BarrierPipeLine[T any] interface {
 	Run(ctx context.Context, in <-chan T) (finalOut <-chan T, mergedErr <-chan error)
 }
+
+// DAGPipeLine defines a pipeline whose stages form a directed
+// acyclic graph. Items from `in` are sent to every root stage,
+// each stage's output is duplicated to all of its downstream
+// stages (fan-out), and a stage with several upstream stages
+// receives their merged outputs (fan-in). The outputs of all
+// leaf stages are merged into `out` and the errors of every
+// stage into `errMerged`.
+type DAGPipeLine[T any] interface {
+	Run(ctx context.Context, in <-chan T) (out <-chan T, errMerged <-chan error)
+}
+
+// BatchPipeLine runs a batch of items through a pipeline of any kind and
+// returns the items that made it through together with the errors of the
+// ones that did not. Triggers (HTTP routes, Kafka topics) use it to start
+// pipelines declared in the config.
+type BatchPipeLine[T any] interface {
+	Process(ctx context.Context, items []T) ([]T, []error)
+}
@@ -14,8 +14,38 @@ type Stage[T any] interface {
 	Run(ctx context.Context, in <-chan T) (out <-chan T, err <-chan error)
 }
 
+// Transformer is a stage that consumes values of one type and emits values
+// of another, e.g. raw message bytes into a domain model. It follows the same
+// contract as Stage: both output channels must be closed by the transformer
+// when processing is complete. Stage[T] has the same method set as
+// Transformer[T, T], so every Stage is also a Transformer.
+type Transformer[In, Out any] interface {
+	Name() string
+	Run(ctx context.Context, in <-chan In) (out <-chan Out, err <-chan error)
+}
+
 // StageFn represents a function-based stage for short-circuit pipelines.
 // Unlike Stage, this processes a single value at a time rather than streams
 // from a channel. If an error is returned, the pipeline is immediately
 // stopped (short-circuited) and the error is propagated.
 type StageFn[T any] func(ctx context.Context, m T) (T, error)
+
+// Compensator undoes the side effects of a StageFn that completed before a
+// later stage of a short-circuit pipeline failed (saga-style rollback).
+// It receives the value that the completed stage returned.
+type Compensator[T any] func(ctx context.Context, m T) error
+
+// ItemStage is a Stage that processes one item at a time. Runners drive
+// such stages themselves and call Process for every item in a recovered
+// call, so a panic fails only that item instead of the whole process.
+// Process returns the item to pass on, or the error the item failed with.
+type ItemStage[T any] interface {
+	Stage[T]
+	Process(ctx context.Context, m T) (T, error)
+}
+
+// PanicStopper is implemented by item stages that stop after a panic, once
+// the failed item is reported, instead of going on with the next item.
+type PanicStopper interface {
+	StopOnPanic() bool
+}
@@ -0,0 +1,37 @@
+package ports
+
+// OptionKind is the value type of a stage option.
+type OptionKind string
+
+const (
+	OptionString   OptionKind = "string"
+	OptionInt      OptionKind = "int"
+	OptionBool     OptionKind = "bool"
+	OptionDuration OptionKind = "duration"
+)
+
+// OptionSpec describes one option of a registered stage. Default is used
+// when the option is not set and must parse as Kind.
+type OptionSpec struct {
+	Name        string     `json:"name"`
+	Kind        OptionKind `json:"kind"`
+	Default     string     `json:"default,omitempty"`
+	Required    bool       `json:"required,omitempty"`
+	Description string     `json:"description"`
+}
+
+// StageSpec describes a registered stage: what it does, the options it
+// takes and which forms it is written in (channel Stage, StageFn). Every
+// stage can be built in both forms; the missing one is adapted.
+type StageSpec struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Options     []OptionSpec `json:"options,omitempty"`
+	Stage       bool         `json:"stage"`
+	Fn          bool         `json:"fn"`
+}
+
+// StageCatalog lists the registered stages, sorted by name.
+type StageCatalog interface {
+	Specs() []StageSpec
+}
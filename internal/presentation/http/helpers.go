@@ -1,11 +1,46 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 
 	"go-pipeline/internal/model"
+	"go-pipeline/pkg/apperror"
 )
 
+// failureReport describes one failed item in an HTTP response.
+type failureReport struct {
+	Stage   string          `json:"stage,omitempty"`
+	Item    *model.UserData `json:"item,omitempty"`
+	Attempt int             `json:"attempt,omitempty"`
+	Error   string          `json:"error"`
+}
+
+// failureReports turns pipeline errors into per-item failure reports.
+// Errors that are not stage errors are reported with their message only,
+// and wrapped stage errors keep the full message of the wrapper.
+func failureReports(errs []error) []failureReport {
+	reports := make([]failureReport, 0, len(errs))
+	for _, err := range errs {
+		report := failureReport{Error: err.Error()}
+		if se, ok := apperror.AsStageError[model.UserData](err); ok {
+			report.Stage = se.Stage
+			report.Attempt = se.Attempt
+			if err == error(se) {
+				report.Error = se.Err.Error()
+			}
+			if se.Item != (model.UserData{}) {
+				item := se.Item
+				report.Item = &item
+			}
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
 func drainAll(
 	ctx context.Context,
 	out <-chan model.UserData,
@@ -33,3 +68,20 @@ func drainAll(
 	}
 	return items, errs, false
 }
+
+// decodeUsers decodes a JSON body holding one user or an array of users.
+func decodeUsers(body []byte) ([]model.UserData, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var users []model.UserData
+		if err := json.Unmarshal(body, &users); err != nil {
+			return nil, fmt.Errorf("%w: %v", apperror.ErrInvalidInput, err)
+		}
+		return users, nil
+	}
+	var user model.UserData
+	if err := json.Unmarshal(body, &user); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperror.ErrInvalidInput, err)
+	}
+	return []model.UserData{user}, nil
+}
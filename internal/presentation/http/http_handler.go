@@ -1,7 +1,10 @@
 package http
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
 
 	"go-pipeline/config"
 	"go-pipeline/internal/model"
@@ -16,18 +19,25 @@ type GinAdapter struct {
 	pipeline    ports.ChainPipeline[model.UserData]
 	shortRunner ports.ShortCircuitPipeLine[model.UserData]
 	barrier     ports.BarrierPipeLine[model.UserData]
+	dag         ports.DAGPipeLine[model.UserData]
+	checks      []ports.HealthChecker
+	layer       *gin.RouterGroup
 }
 
 func NewGinAdapter(
 	p ports.ChainPipeline[model.UserData],
 	b ports.BarrierPipeLine[model.UserData],
 	sr ports.ShortCircuitPipeLine[model.UserData],
+	d ports.DAGPipeLine[model.UserData],
+	checks ...ports.HealthChecker,
 ) *GinAdapter {
 	adapter := &GinAdapter{
 		Engin:       ginEngin(),
 		pipeline:    p,
 		barrier:     b,
 		shortRunner: sr,
+		dag:         d,
+		checks:      checks,
 	}
 	adapter.handleRoutes()
 	return adapter
@@ -46,14 +56,59 @@ func ginEngin() *gin.Engine {
 }
 
 func (g *GinAdapter) handleRoutes() {
+	g.healthCheck(g.Engin)
+
 	// TODO:1: change name to yours
 
-	layer := g.Engin.Group("/boiler")
-	layer.Use(middleware.TraceIDGenerator())
+	g.layer = g.Engin.Group("/boiler")
+	g.layer.Use(middleware.TraceIDGenerator())
 
-	g.testParallel(layer)
-	g.testBarrier(layer)
-	g.testShort(layer)
+	g.testParallel(g.layer)
+	g.testBarrier(g.layer)
+	g.testShort(g.layer)
+	g.testDAG(g.layer)
+}
+
+// Handle serves a pipeline declared in the config under POST route. The
+// body is a single user or an array of users.
+func (g *GinAdapter) Handle(
+	name, route string,
+	p ports.BatchPipeLine[model.UserData],
+) {
+	g.layer.POST(route, func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, config.MaxBodyBytes))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		users, err := decodeUsers(body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		items, errs := p.Process(ctx, users)
+		if ctx.Err() != nil {
+			c.JSON(499, gin.H{"error": "client canceled"})
+			return
+		}
+		if errs != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": failureReports(errs)})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"pipeline": name,
+			"count":    len(items),
+			"items":    items,
+		})
+	})
 }
 
 func selectMode(debug bool) string {
@@ -85,7 +140,7 @@ func (g *GinAdapter) testParallel(r *gin.RouterGroup) {
 		}
 
 		if errs != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": errs})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": failureReports(errs)})
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{
@@ -112,7 +167,7 @@ func (g *GinAdapter) testBarrier(r *gin.RouterGroup) {
 			return
 		}
 		if errs != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": errs})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": failureReports(errs)})
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{
@@ -133,11 +188,79 @@ func (g *GinAdapter) testShort(r *gin.RouterGroup) {
 			Email: "V3@gmailcom",
 		}
 
+		// ?debug=true returns the per-stage trace; the input/output snapshots
+		// hold user data, so only a debug build adds them
+		debug, _ := strconv.ParseBool(c.Query("debug"))
+		if debug {
+			out, trace, err := g.shortRunner.RunTraced(ctx, user, config.Get().AppConfig.Debug)
+			if err != nil {
+				c.JSON(500, gin.H{"error": failureReports([]error{err}), "trace": trace})
+				return
+			}
+			c.JSON(200, gin.H{"data": out, "trace": trace})
+			return
+		}
+
 		out, err := g.shortRunner.Run(ctx, user)
 		if err != nil {
-			c.JSON(500, gin.H{"error": err.Error()})
+			c.JSON(500, gin.H{"error": failureReports([]error{err})})
 			return
 		}
 		c.JSON(200, gin.H{"data": out})
 	})
 }
+
+func (g *GinAdapter) testDAG(r *gin.RouterGroup) {
+	r.GET("/v4", func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		in := make(chan model.UserData, 1)
+		in <- model.UserData{Name: "mohsenV4", Age: 30, Email: "V4@gmail.com"}
+		close(in)
+
+		out, errChan := g.dag.Run(ctx, in)
+
+		items, errs, canceled := drainAll(ctx, out, errChan)
+		if canceled {
+			c.JSON(499, gin.H{"error": "client canceled"})
+			return
+		}
+		if errs != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": failureReports(errs)})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"pipeline": "dag",
+			"count":    len(items),
+			"items":    items,
+		})
+	})
+}
+
+// ServeStages lists the stages of catalog under GET /admin/stages, with
+// their descriptions and option schemas.
+func (g *GinAdapter) ServeStages(catalog ports.StageCatalog) {
+	g.Engin.GET("/admin/stages", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"stages": catalog.Specs()})
+	})
+}
+
+// healthCheck serves /hc for container health checks. It reports every
+// registered check and answers 503 if any of them fails.
+func (g *GinAdapter) healthCheck(r gin.IRoutes) {
+	r.GET("/hc", func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		status := http.StatusOK
+		checks := make(gin.H, len(g.checks))
+		for _, hc := range g.checks {
+			if err := hc.Check(ctx); err != nil {
+				status = http.StatusServiceUnavailable
+				checks[hc.Name()] = err.Error()
+				continue
+			}
+			checks[hc.Name()] = "ok"
+		}
+		c.JSON(status, gin.H{"status": http.StatusText(status), "checks": checks})
+	})
+}
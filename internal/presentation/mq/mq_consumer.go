@@ -1,18 +1,44 @@
 package mq
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 
+	"go-pipeline/config"
+	"go-pipeline/internal/model"
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+	"go-pipeline/pkg/generate"
 	"go-pipeline/pkg/logger"
 
 	"github.com/IBM/sarama"
 )
 
 // ConsumerHandler for handling consume message
-type ConsumerHandler struct{}
+type ConsumerHandler struct {
+	routes     map[string]ports.BatchPipeLine[model.UserData]
+	deadLetter ports.DeadLetterSink[string]
+}
 
 func NewConsumerHandler() *ConsumerHandler {
-	return &ConsumerHandler{}
+	return &ConsumerHandler{routes: make(map[string]ports.BatchPipeLine[model.UserData])}
+}
+
+// Route runs every user consumed from topic through p. Routes must be set
+// before the consumer starts.
+func (h *ConsumerHandler) Route(topic string, p ports.BatchPipeLine[model.UserData]) {
+	h.routes[topic] = p
+}
+
+// WithDeadLetter routes the raw value of messages that cannot be decoded
+// into a user to sink. Without one they are only logged.
+func (h *ConsumerHandler) WithDeadLetter(sink ports.DeadLetterSink[string]) *ConsumerHandler {
+	h.deadLetter = sink
+	return h
 }
 
 func (h *ConsumerHandler) Setup(sarama.ConsumerGroupSession) error {
@@ -46,7 +72,72 @@ func (h *ConsumerHandler) ConsumeClaim(
 			},
 		})
 
+		if p, ok := h.routes[msg.Topic]; ok {
+			h.process(session.Context(), p, msg)
+		}
+
 		session.MarkMessage(msg, "done")
 	}
 	return nil
 }
+
+// process runs a consumed message through its pipeline. Failures are
+// logged; the message is marked either way, failed items reach the dead
+// letter topic through the pipeline and undecodable messages through the
+// handler's own sink.
+func (h *ConsumerHandler) process(
+	ctx context.Context,
+	p ports.BatchPipeLine[model.UserData],
+	msg *sarama.ConsumerMessage,
+) {
+	traceID := generate.TraceID()
+	ctx = context.WithValue(ctx, config.TraceIDKey, traceID)
+
+	var user model.UserData
+	if err := json.Unmarshal(msg.Value, &user); err != nil {
+		err = fmt.Errorf("%w: decode user: %w", apperror.ErrInvalidInput, err)
+		logger.GetLogger().Error(&logger.Log{
+			Event:      "consume decode",
+			Error:      err,
+			TraceID:    traceID,
+			Additional: map[string]interface{}{"topic": msg.Topic, "offset": msg.Offset},
+		})
+		h.deadLetterRaw(ctx, msg, err)
+		return
+	}
+	if _, errs := p.Process(ctx, []model.UserData{user}); errs != nil {
+		logger.GetLogger().Error(&logger.Log{
+			Event:      "consume pipeline",
+			Error:      errors.Join(errs...),
+			TraceID:    traceID,
+			Additional: map[string]interface{}{"topic": msg.Topic, "offset": msg.Offset},
+		})
+	}
+}
+
+// deadLetterRaw hands the raw value of a message that failed to decode to
+// the dead letter sink.
+func (h *ConsumerHandler) deadLetterRaw(
+	ctx context.Context,
+	msg *sarama.ConsumerMessage,
+	err error,
+) {
+	if h.deadLetter == nil {
+		return
+	}
+	meta := map[string]string{
+		pipelines.MetaStage:     "consume_decode",
+		pipelines.MetaErrorCode: apperror.JobCode(err),
+		pipelines.MetaAttempt:   "1",
+		"topic":                 msg.Topic,
+		"offset":                strconv.FormatInt(msg.Offset, 10),
+	}
+	if errDL := h.deadLetter.DeadLetter(ctx, string(msg.Value), err, meta); errDL != nil {
+		logger.GetLogger().Error(&logger.Log{
+			Event:      "consume dead letter",
+			Error:      errDL,
+			TraceID:    config.GetTraceID(ctx),
+			Additional: map[string]interface{}{"topic": msg.Topic, "offset": msg.Offset},
+		})
+	}
+}
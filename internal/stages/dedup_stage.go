@@ -0,0 +1,87 @@
+package stages
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go-pipeline/config"
+	"go-pipeline/internal/model"
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
+)
+
+// Options of the stages that produce, which produce every user once per
+// ttl.
+const (
+	optDedup    = "dedup"
+	optDedupTTL = "dedup_ttl"
+)
+
+// dedupOff turns the deduplication of a produce stage off.
+const dedupOff = "off"
+
+// dedupModes maps the dedup option to the pipelines mode.
+var dedupModes = map[string]pipelines.DedupMode{
+	"skip":   pipelines.DedupSkip,
+	"reject": pipelines.DedupReject,
+}
+
+var dedupOptions = []ports.OptionSpec{
+	{
+		Name:        optDedup,
+		Kind:        ports.OptionString,
+		Default:     "skip",
+		Description: "what happens to users already produced: skip, reject or off",
+	},
+	{
+		Name:        optDedupTTL,
+		Kind:        ports.OptionDuration,
+		Default:     config.DedupTTL.String(),
+		Description: "time a produced user is remembered",
+	},
+}
+
+// UserKey identifies a user across redeliveries and resubmissions: the
+// email, ignoring case and surrounding spaces.
+func UserKey(m model.UserData) string {
+	return strings.ToLower(strings.TrimSpace(m.Email))
+}
+
+// NewDedupStage calls fn once per user and ttl. Duplicates are skipped or,
+// with pipelines.DedupReject, fail with apperror.ErrDuplicateEntry. A user
+// fn fails for is forgotten, so it can be retried.
+func NewDedupStage(
+	name string,
+	fn ports.StageFn[model.UserData],
+	store ports.DedupStore,
+	ttl time.Duration,
+	mode pipelines.DedupMode,
+) *pipelines.DedupStage[model.UserData] {
+	return pipelines.NewDedupStage(name, UserKey, fn, store, ttl).WithMode(mode)
+}
+
+// dedupStage turns produce, the function of the stage name, into a stage
+// that deduplicates as its dedup options say.
+func dedupStage(
+	name string,
+	produce ports.StageFn[model.UserData],
+	deps Deps,
+	opts pipelines.Options,
+) (ports.ItemStage[model.UserData], error) {
+	if opts.String(optDedup) == dedupOff {
+		return pipelines.Map(name, produce), nil
+	}
+	mode, ok := dedupModes[opts.String(optDedup)]
+	if !ok {
+		return nil, fmt.Errorf("%w: option %q must be skip, reject or off",
+			apperror.ErrInvalidInput, optDedup)
+	}
+	ttl := opts.Duration(optDedupTTL)
+	if ttl <= 0 {
+		return nil, fmt.Errorf("%w: option %q must be positive",
+			apperror.ErrInvalidInput, optDedupTTL)
+	}
+	return NewDedupStage(name, produce, deps.Dedup, ttl, mode), nil
+}
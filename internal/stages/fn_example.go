@@ -2,21 +2,14 @@ package stages
 
 import (
 	"context"
-	"errors"
+	"slices"
 
 	"go-pipeline/internal/model"
+	"go-pipeline/internal/pipelines"
 	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
 )
 
-func ValidationFn() ports.StageFn[model.UserData] {
-	return func(ctx context.Context, m model.UserData) (model.UserData, error) {
-		if m.Email == "" || !containsAt2(m.Email) {
-			return m, errors.New("validation: invalid email")
-		}
-		return m, nil
-	}
-}
-
 func TransformFn() ports.StageFn[model.UserData] {
 	return func(ctx context.Context, m model.UserData) (model.UserData, error) {
 		if m.Name == "" {
@@ -26,17 +19,36 @@ func TransformFn() ports.StageFn[model.UserData] {
 	}
 }
 
-func SinkFn(p ports.MessageQueueProducer) ports.StageFn[model.UserData] {
+func SinkFn(p ports.MessageQueueProducer, topic string) ports.StageFn[model.UserData] {
 	return func(ctx context.Context, m model.UserData) (model.UserData, error) {
-		return m, p.Produce(ctx, "users", m)
+		if err := p.Produce(ctx, topic, m); err != nil {
+			return m, apperror.NewStageError("sink", m, 1, err)
+		}
+		return m, nil
 	}
 }
 
-func containsAt2(s string) bool {
-	for i := range s {
-		if s[i] == '@' {
-			return true
-		}
-	}
-	return false
+func init() {
+	Registry.MustRegister(pipelines.StageFactory[model.UserData, Deps]{
+		Name:        "transform",
+		Description: "names anonymous users",
+		Fn: func(Deps, pipelines.Options) (ports.StageFn[model.UserData], error) {
+			return TransformFn(), nil
+		},
+	})
+	Registry.MustRegister(pipelines.StageFactory[model.UserData, Deps]{
+		Name:        "sink",
+		Description: "produces a user once per dedup_ttl at the shared produce rate, with retries",
+		Options:     slices.Concat(produceOptions, dedupOptions),
+		Fn: func(deps Deps, opts pipelines.Options) (ports.StageFn[model.UserData], error) {
+			sink := pipelines.RateLimitFn("sink", SinkFn(deps.Producer, opts.String(optTopic)),
+				deps.Limiter)
+			sink = pipelines.RetryFn("sink", sink, deps.Retry)
+			stage, err := dedupStage("sink", sink, deps, opts)
+			if err != nil {
+				return nil, err
+			}
+			return stage.Process, nil
+		},
+	})
 }
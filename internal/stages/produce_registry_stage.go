@@ -2,50 +2,42 @@ package stages
 
 import (
 	"context"
-
-	"go-pipeline/config"
+	"slices"
 
 	"go-pipeline/internal/model"
+	"go-pipeline/internal/pipelines"
 	"go-pipeline/internal/ports"
 )
 
-type ProduceRegistryStage struct {
-	producer ports.MessageQueueProducer
-}
-
-func NewProduceRegistryStage(producer ports.MessageQueueProducer) *ProduceRegistryStage {
-	return &ProduceRegistryStage{producer: producer}
+// NewProduceRegistryStage produces every user to topic.
+func NewProduceRegistryStage(
+	producer ports.MessageQueueProducer,
+	topic string,
+) ports.ItemStage[model.UserData] {
+	return pipelines.Map("produce-registry",
+		func(ctx context.Context, m model.UserData) (model.UserData, error) {
+			return m, producer.Produce(ctx, topic, m)
+		})
 }
 
-func (p *ProduceRegistryStage) Name() string { return "produce-registry" }
-
-func (p *ProduceRegistryStage) Run(
-	ctx context.Context,
-	in <-chan model.UserData,
-) (<-chan model.UserData, <-chan error) {
-	out := make(chan model.UserData, config.BuffData)
-	err := make(chan error, config.BuffErr)
-
-	go func() {
-		defer close(out)
-		defer close(err)
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case m, ok := <-in:
-				if !ok {
-					return
-				}
-				if errP := p.producer.Produce(ctx, "users", m); errP != nil {
-					err <- errP
-					continue
-				}
-				out <- m
-			}
-		}
-	}()
-	return out, err
+func init() {
+	Registry.MustRegister(pipelines.StageFactory[model.UserData, Deps]{
+		Name:        "produce-registry",
+		Description: "produces every user once per dedup_ttl, retrying sends that surely failed",
+		Options:     slices.Concat(produceOptions, dedupOptions),
+		Stage: func(deps Deps, opts pipelines.Options) (ports.Stage[model.UserData], error) {
+			// no timeout around the produce: the producer bounds its own sends
+			// and ignores ctx, so an abandoned send could still go through
+			produce := NewProduceRegistryStage(deps.Producer, opts.String(optTopic))
+			retried := pipelines.RetryFn("produce-registry", produce.Process, deps.Retry)
+			return dedupStage("produce-registry", retried, deps, opts)
+		},
+	})
+	Registry.MustRegister(pipelines.StageFactory[model.UserData, Deps]{
+		Name:        "produce_throttle",
+		Description: "holds users back to the shared produce rate",
+		Stage: func(deps Deps, _ pipelines.Options) (ports.Stage[model.UserData], error) {
+			return pipelines.NewRateLimitStage("produce_throttle", deps.Limiter), nil
+		},
+	})
 }
-
-var _ ports.Stage[model.UserData] = (*ProduceRegistryStage)(nil)
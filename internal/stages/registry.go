@@ -0,0 +1,36 @@
+package stages
+
+import (
+	"go-pipeline/config"
+	"go-pipeline/internal/model"
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/internal/ports"
+)
+
+// Deps holds what the registered stages depend on.
+type Deps struct {
+	// Producer sends messages to Kafka.
+	Producer ports.MessageQueueProducer
+	// Retry is applied to every produce attempt.
+	Retry pipelines.RetryPolicy
+	// Limiter is shared by every stage that produces.
+	Limiter *pipelines.RateLimiter[model.UserData]
+	// Dedup remembers the users that were already produced.
+	Dedup ports.DedupStore
+}
+
+// Registry holds every stage of this package under its Name. The stages
+// register themselves from init.
+var Registry = pipelines.NewStageRegistry[model.UserData, Deps]()
+
+// Options of the stages that produce.
+const optTopic = "topic"
+
+var produceOptions = []ports.OptionSpec{
+	{
+		Name:        optTopic,
+		Kind:        ports.OptionString,
+		Default:     config.UsersTopic,
+		Description: "Kafka topic the users are produced to",
+	},
+}
@@ -3,47 +3,25 @@ package stages
 import (
 	"context"
 
-	"go-pipeline/config"
-
-	"go-pipeline/internal/ports"
-
 	"go-pipeline/internal/model"
+	"go-pipeline/internal/pipelines"
+	"go-pipeline/internal/ports"
 )
 
-type StoreRegistryStage struct{}
-
-func NewStoreRegistryStage() *StoreRegistryStage {
-	return &StoreRegistryStage{}
+func NewStoreRegistryStage() ports.ItemStage[model.UserData] {
+	return pipelines.Map("store_registry",
+		func(ctx context.Context, m model.UserData) (model.UserData, error) {
+			// TODO: store to DB/Cache
+			return m, nil
+		})
 }
 
-func (s *StoreRegistryStage) Name() string {
-	return "store_registry"
+func init() {
+	Registry.MustRegister(pipelines.StageFactory[model.UserData, Deps]{
+		Name:        "store_registry",
+		Description: "stores the users",
+		Stage: func(Deps, pipelines.Options) (ports.Stage[model.UserData], error) {
+			return NewStoreRegistryStage(), nil
+		},
+	})
 }
-
-func (s *StoreRegistryStage) Run(
-	ctx context.Context,
-	in <-chan model.UserData,
-) (<-chan model.UserData, <-chan error) {
-	out := make(chan model.UserData, config.BuffData)
-	err := make(chan error, config.BuffErr)
-
-	go func() {
-		defer close(out)
-		defer close(err)
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case m, ok := <-in:
-				if !ok {
-					return
-				}
-				// TODO: store to DB/Cache
-				out <- m
-			}
-		}
-	}()
-	return out, err
-}
-
-var _ ports.Stage[model.UserData] = (*StoreRegistryStage)(nil)
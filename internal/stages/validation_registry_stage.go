@@ -2,64 +2,38 @@ package stages
 
 import (
 	"context"
-	"errors"
-
-	"go-pipeline/config"
+	"fmt"
+	"strings"
 
 	"go-pipeline/internal/model"
+	"go-pipeline/internal/pipelines"
 	"go-pipeline/internal/ports"
+	"go-pipeline/pkg/apperror"
 )
 
-type ValidationRegistryStage struct{}
-
-func NewValidationRegistryStage() *ValidationRegistryStage {
-	return &ValidationRegistryStage{}
+func NewValidationRegistryStage() ports.ItemStage[model.UserData] {
+	return pipelines.Map("validation_registry",
+		func(ctx context.Context, m model.UserData) (model.UserData, error) {
+			return m, validateEmail(m)
+		})
 }
 
-func (v *ValidationRegistryStage) Name() string {
-	return "validation_registry"
-}
-
-func (v *ValidationRegistryStage) Run(
-	ctx context.Context,
-	in <-chan model.UserData,
-) (<-chan model.UserData, <-chan error) {
-	out := make(chan model.UserData, config.BuffData)
-	err := make(chan error, config.BuffErr)
-
-	go func() {
-		defer close(out)
-		defer close(err)
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case m, ok := <-in:
-				if !ok {
-					return
-				}
-				if m.Email == "" {
-					err <- errors.New("email address is required")
-					continue
-				}
-				if !containsAt(m.Email) {
-					err <- errors.New("email address is invalid")
-					continue
-				}
-				out <- m
-			}
-		}
-	}()
-	return out, err
+func validateEmail(m model.UserData) error {
+	if m.Email == "" {
+		return fmt.Errorf("%w: email address is required", apperror.ErrInvalidInput)
+	}
+	if !strings.Contains(m.Email, "@") {
+		return fmt.Errorf("%w: email address is invalid", apperror.ErrInvalidInput)
+	}
+	return nil
 }
 
-var _ ports.Stage[model.UserData] = (*ValidationRegistryStage)(nil)
-
-func containsAt(s string) bool {
-	for i := range s {
-		if s[i] == '@' {
-			return true
-		}
-	}
-	return false
+func init() {
+	Registry.MustRegister(pipelines.StageFactory[model.UserData, Deps]{
+		Name:        "validation_registry",
+		Description: "rejects users without a valid email address",
+		Stage: func(Deps, pipelines.Options) (ports.Stage[model.UserData], error) {
+			return NewValidationRegistryStage(), nil
+		},
+	})
 }
@@ -0,0 +1,41 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+)
+
+// StageError reports a pipeline item that failed in a stage.
+// It carries the failing item, the stage name, the number of attempts made
+// and the underlying cause, which stays reachable through errors.Is/As.
+type StageError[T any] struct {
+	Stage   string
+	Item    T
+	Attempt int
+	Err     error
+}
+
+// NewStageError creates a StageError for item failing in stage.
+func NewStageError[T any](stage string, item T, attempt int, err error) *StageError[T] {
+	return &StageError[T]{
+		Stage:   stage,
+		Item:    item,
+		Attempt: attempt,
+		Err:     err,
+	}
+}
+
+func (e *StageError[T]) Error() string {
+	return fmt.Sprintf("stage %s (attempt %d): %v", e.Stage, e.Attempt, e.Err)
+}
+
+func (e *StageError[T]) Unwrap() error { return e.Err }
+
+// AsStageError finds the first StageError[T] in err's chain.
+func AsStageError[T any](err error) (*StageError[T], bool) {
+	var se *StageError[T]
+	if errors.As(err, &se) {
+		return se, true
+	}
+	return nil, false
+}
@@ -0,0 +1,34 @@
+package apperror_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"go-pipeline/pkg/apperror"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStageError(t *testing.T) {
+	type item struct{ ID int }
+
+	cause := fmt.Errorf("%w: email address is invalid", apperror.ErrInvalidInput)
+	err := fmt.Errorf("runner: %w", apperror.NewStageError("validation", item{ID: 7}, 2, cause))
+
+	var se *apperror.StageError[item]
+	assert.True(t, errors.As(err, &se))
+	assert.Equal(t, "validation", se.Stage)
+	assert.Equal(t, item{ID: 7}, se.Item)
+	assert.Equal(t, 2, se.Attempt)
+	assert.ErrorIs(t, err, apperror.ErrInvalidInput)
+	assert.Equal(t, http.StatusBadRequest, apperror.HTTPStatus(err))
+	assert.Equal(t,
+		"runner: stage validation (attempt 2): invalid input: email address is invalid",
+		err.Error(),
+	)
+
+	_, ok := apperror.AsStageError[string](err)
+	assert.False(t, ok)
+}